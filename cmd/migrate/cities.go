@@ -0,0 +1,203 @@
+package main
+
+import (
+	"gorm.io/gorm"
+	"rip-go-app/internal/app/ds"
+	"rip-go-app/internal/app/repository"
+)
+
+// cityRow - город для начального заполнения справочника
+type cityRow struct {
+	Name      string
+	Region    string
+	Latitude  float64
+	Longitude float64
+	Aliases   []string
+}
+
+// routeRow - известное расстояние по дорогам между двумя городами
+type routeRow struct {
+	From       string
+	To         string
+	DistanceKm float64
+}
+
+// seedCities - начальное заполнение справочника городов и таблицы расстояний
+// (данные перенесены из прежней таблицы расстояний калькулятора)
+func seedCities(db *gorm.DB) {
+	cities := []cityRow{
+		{"Москва", "Москва", 55.7558, 37.6173, []string{"мск", "moscow"}},
+		{"Санкт-Петербург", "Санкт-Петербург", 59.9343, 30.3351, []string{"спб", "питер", "с-петербург", "saint petersburg", "st petersburg"}},
+		{"Екатеринбург", "Свердловская область", 56.8389, 60.6057, []string{"екб", "yekaterinburg"}},
+		{"Новосибирск", "Новосибирская область", 55.0084, 82.9357, []string{"нск"}},
+		{"Красноярск", "Красноярский край", 56.0153, 92.8932, nil},
+		{"Иркутск", "Иркутская область", 52.2870, 104.3050, nil},
+		{"Владивосток", "Приморский край", 43.1155, 131.8855, nil},
+		{"Ростов-на-Дону", "Ростовская область", 47.2357, 39.7015, []string{"ростов"}},
+		{"Сочи", "Краснодарский край", 43.5855, 39.7231, nil},
+		{"Казань", "Республика Татарстан", 55.7963, 49.1088, nil},
+		{"Нижний Новгород", "Нижегородская область", 56.2965, 43.9361, []string{"нн"}},
+		{"Самара", "Самарская область", 53.1959, 50.1002, nil},
+		{"Волгоград", "Волгоградская область", 48.7080, 44.5133, nil},
+		{"Воронеж", "Воронежская область", 51.6720, 39.1843, nil},
+		{"Саратов", "Саратовская область", 51.5336, 46.0343, nil},
+		{"Пермь", "Пермский край", 58.0105, 56.2502, nil},
+		{"Уфа", "Республика Башкортостан", 54.7388, 55.9721, nil},
+		{"Челябинск", "Челябинская область", 55.1644, 61.4368, nil},
+		{"Омск", "Омская область", 54.9885, 73.3242, nil},
+		{"Тюмень", "Тюменская область", 57.1530, 65.5343, nil},
+		{"Краснодар", "Краснодарский край", 45.0355, 38.9753, nil},
+		{"Новороссийск", "Краснодарский край", 44.7235, 37.7686, nil},
+		{"Ставрополь", "Ставропольский край", 45.0428, 41.9734, nil},
+		{"Астрахань", "Астраханская область", 46.3479, 48.0336, nil},
+		{"Махачкала", "Республика Дагестан", 42.9849, 47.5047, nil},
+		{"Грозный", "Чеченская Республика", 43.3178, 45.6949, nil},
+		{"Элиста", "Республика Калмыкия", 46.3078, 44.2558, nil},
+		{"Йошкар-Ола", "Республика Марий Эл", 56.6344, 47.8999, nil},
+		{"Чебоксары", "Чувашская Республика", 56.1439, 47.2489, nil},
+		{"Ижевск", "Удмуртская Республика", 56.8526, 53.2045, nil},
+		{"Киров", "Кировская область", 58.6036, 49.6680, nil},
+		{"Сыктывкар", "Республика Коми", 61.6688, 50.8364, nil},
+		{"Архангельск", "Архангельская область", 64.5393, 40.5170, nil},
+		{"Мурманск", "Мурманская область", 68.9585, 33.0827, nil},
+		{"Петрозаводск", "Республика Карелия", 61.7849, 34.3469, nil},
+		{"Калининград", "Калининградская область", 54.7104, 20.4522, nil},
+		{"Великий Новгород", "Новгородская область", 58.5213, 31.2755, []string{"новгород"}},
+		{"Псков", "Псковская область", 57.8194, 28.3318, nil},
+		{"Тверь", "Тверская область", 56.8587, 35.9176, nil},
+		{"Вологда", "Вологодская область", 59.2181, 39.8886, nil},
+		{"Череповец", "Вологодская область", 59.1269, 37.9090, nil},
+		{"Курган", "Курганская область", 55.4410, 65.3411, nil},
+		{"Оренбург", "Оренбургская область", 51.7682, 55.0969, nil},
+		{"Магнитогорск", "Челябинская область", 53.4186, 58.9790, nil},
+		{"Томск", "Томская область", 56.4846, 84.9476, nil},
+		{"Барнаул", "Алтайский край", 53.3481, 83.7798, nil},
+		{"Кемерово", "Кемеровская область", 55.3547, 86.0873, nil},
+		{"Новокузнецк", "Кемеровская область", 53.7557, 87.1099, nil},
+		{"Бийск", "Алтайский край", 52.5394, 85.2072, nil},
+		{"Горно-Алтайск", "Республика Алтай", 51.9581, 85.9603, nil},
+		{"Абакан", "Республика Хакасия", 53.7151, 91.4292, nil},
+		{"Кызыл", "Республика Тыва", 51.7191, 94.4378, nil},
+		{"Норильск", "Красноярский край", 69.3558, 88.1893, nil},
+		{"Дудинка", "Красноярский край", 69.4058, 86.1778, nil},
+		{"Улан-Удэ", "Республика Бурятия", 51.8335, 107.5841, nil},
+		{"Чита", "Забайкальский край", 52.0515, 113.4712, nil},
+		{"Якутск", "Республика Саха (Якутия)", 62.0355, 129.6755, nil},
+		{"Магадан", "Магаданская область", 59.5612, 150.8301, nil},
+		{"Петропавловск-Камчатский", "Камчатский край", 53.0452, 158.6483, []string{"петропавловск"}},
+		{"Хабаровск", "Хабаровский край", 48.4802, 135.0719, nil},
+		{"Южно-Сахалинск", "Сахалинская область", 46.9591, 142.7380, nil},
+		{"Благовещенск", "Амурская область", 50.2907, 127.5272, nil},
+	}
+
+	routes := []routeRow{
+		{"Москва", "Санкт-Петербург", 635},
+		{"Москва", "Екатеринбург", 1416},
+		{"Москва", "Новосибирск", 3354},
+		{"Москва", "Красноярск", 4205},
+		{"Москва", "Иркутск", 5152},
+		{"Москва", "Владивосток", 9100},
+		{"Москва", "Ростов-на-Дону", 1070},
+		{"Москва", "Сочи", 1360},
+		{"Москва", "Казань", 820},
+		{"Москва", "Нижний Новгород", 420},
+		{"Москва", "Самара", 1050},
+		{"Москва", "Волгоград", 970},
+		{"Москва", "Воронеж", 520},
+		{"Москва", "Саратов", 850},
+		{"Москва", "Пермь", 1380},
+		{"Москва", "Уфа", 1160},
+		{"Москва", "Челябинск", 1510},
+		{"Москва", "Омск", 2550},
+		{"Москва", "Тюмень", 1720},
+		{"Москва", "Краснодар", 1350},
+		{"Москва", "Ставрополь", 1400},
+		{"Москва", "Астрахань", 1400},
+		{"Москва", "Махачкала", 1800},
+		{"Москва", "Грозный", 1900},
+		{"Москва", "Элиста", 1200},
+		{"Москва", "Йошкар-Ола", 650},
+		{"Москва", "Чебоксары", 650},
+		{"Москва", "Ижевск", 1200},
+		{"Москва", "Киров", 900},
+		{"Москва", "Сыктывкар", 1400},
+		{"Москва", "Архангельск", 1200},
+		{"Москва", "Мурманск", 1900},
+		{"Москва", "Петрозаводск", 1000},
+		{"Москва", "Калининград", 1200},
+		{"Санкт-Петербург", "Екатеринбург", 1780},
+		{"Санкт-Петербург", "Новосибирск", 3720},
+		{"Санкт-Петербург", "Калининград", 550},
+		{"Санкт-Петербург", "Мурманск", 1050},
+		{"Санкт-Петербург", "Архангельск", 1130},
+		{"Санкт-Петербург", "Петрозаводск", 320},
+		{"Санкт-Петербург", "Великий Новгород", 180},
+		{"Санкт-Петербург", "Псков", 280},
+		{"Санкт-Петербург", "Тверь", 480},
+		{"Санкт-Петербург", "Вологда", 700},
+		{"Санкт-Петербург", "Череповец", 650},
+		{"Екатеринбург", "Новосибирск", 1940},
+		{"Екатеринбург", "Челябинск", 200},
+		{"Екатеринбург", "Пермь", 360},
+		{"Екатеринбург", "Тюмень", 320},
+		{"Екатеринбург", "Уфа", 520},
+		{"Екатеринбург", "Курган", 380},
+		{"Екатеринбург", "Оренбург", 800},
+		{"Екатеринбург", "Магнитогорск", 300},
+		{"Новосибирск", "Омск", 650},
+		{"Новосибирск", "Красноярск", 850},
+		{"Новосибирск", "Томск", 270},
+		{"Новосибирск", "Барнаул", 230},
+		{"Новосибирск", "Кемерово", 260},
+		{"Новосибирск", "Новокузнецк", 300},
+		{"Новосибирск", "Бийск", 360},
+		{"Новосибирск", "Горно-Алтайск", 450},
+		{"Красноярск", "Санкт-Петербург", 4570},
+		{"Красноярск", "Иркутск", 1060},
+		{"Красноярск", "Абакан", 410},
+		{"Красноярск", "Кызыл", 460},
+		{"Красноярск", "Норильск", 1500},
+		{"Красноярск", "Дудинка", 1600},
+		{"Иркутск", "Санкт-Петербург", 5520},
+		{"Иркутск", "Улан-Удэ", 450},
+		{"Иркутск", "Чита", 1100},
+		{"Иркутск", "Якутск", 2000},
+		{"Иркутск", "Магадан", 3000},
+		{"Иркутск", "Петропавловск-Камчатский", 4000},
+		{"Владивосток", "Санкт-Петербург", 9470},
+		{"Владивосток", "Хабаровск", 760},
+		{"Владивосток", "Южно-Сахалинск", 1000},
+		{"Владивосток", "Благовещенск", 1100},
+		{"Владивосток", "Петропавловск-Камчатский", 2000},
+	}
+
+	cityIDs := make(map[string]int, len(cities))
+	for _, c := range cities {
+		var city ds.City
+		err := db.Where("name = ?", c.Name).First(&city).Error
+		if err != nil {
+			city = ds.City{Name: c.Name, Region: c.Region, Latitude: c.Latitude, Longitude: c.Longitude}
+			db.Create(&city)
+		}
+		cityIDs[c.Name] = city.ID
+
+		for _, alias := range c.Aliases {
+			normalized := repository.NormalizeCityName(alias)
+			var existing ds.CityAlias
+			if err := db.Where("alias = ?", normalized).First(&existing).Error; err != nil {
+				db.Create(&ds.CityAlias{CityID: city.ID, Alias: normalized})
+			}
+		}
+	}
+
+	for _, rt := range routes {
+		fromID, toID := cityIDs[rt.From], cityIDs[rt.To]
+		var count int64
+		db.Model(&ds.RouteDistance{}).
+			Where("(from_city_id = ? AND to_city_id = ?) OR (from_city_id = ? AND to_city_id = ?)", fromID, toID, toID, fromID).
+			Count(&count)
+		if count == 0 {
+			db.Create(&ds.RouteDistance{FromCityID: fromID, ToCityID: toID, DistanceKm: rt.DistanceKm})
+		}
+	}
+}
@@ -80,11 +80,31 @@ func main() {
 		&ds.TransportService{},
 		&ds.LogisticRequest{},
 		&ds.LogisticRequestService{},
+		&ds.LogisticRequestLeg{},
+		&ds.CargoItem{},
+		&ds.City{},
+		&ds.CityAlias{},
+		&ds.RouteDistance{},
+		&ds.Quote{},
+		&ds.QuoteLine{},
+		&ds.ServiceTariff{},
+		&ds.LogisticRequestEvent{},
+		&ds.TrackingCheckpoint{},
+		&ds.JobLease{},
+		&ds.JobRun{},
+		&ds.FilterPreset{},
+		&ds.RefreshToken{},
+		&ds.RevokedToken{},
+		&ds.RevokedSession{},
+		&ds.UserToken{},
 	)
 	if err != nil {
 		panic("cant migrate db")
 	}
 
+	// Полнотекстовый поиск по каталогу услуг
+	createServiceSearchIndex(db)
+
 	// Создаем системных пользователей
 	users := []ds.User{
 		{
@@ -113,6 +133,9 @@ func main() {
 		}
 	}
 
+	// Справочник городов и расстояний
+	seedCities(db)
+
 	// Создаем начальные данные
 	services := []ds.TransportService{
 		{
@@ -124,6 +147,13 @@ func main() {
 			DeliveryDays: 2,
 			MaxWeight:    20000.0,
 			MaxVolume:    80.0,
+			Tariff: ds.Tariff{
+				DistanceRate: 15, WeightRate: 2, VolumeRate: 50,
+				DistancePerDay: 800, MinDeliveryDays: 1,
+				MaxLength: 13.6, MaxWidth: 2.5, MaxHeight: 2.7,
+				ComplexityFactor: 1.0, ComplexityCap: 2.0, ComplexityDaysDivisor: 1,
+				VolumetricDivisor: 3000,
+			},
 		},
 		{
 			ID:           2,
@@ -134,6 +164,13 @@ func main() {
 			DeliveryDays: 1,
 			MaxWeight:    3000.0,
 			MaxVolume:    15.0,
+			Tariff: ds.Tariff{
+				DistanceRate: 12, WeightRate: 3, VolumeRate: 60,
+				DistancePerDay: 600, MinDeliveryDays: 1,
+				MaxLength: 6.0, MaxWidth: 2.0, MaxHeight: 2.2,
+				ComplexityFactor: 1.0, ComplexityCap: 2.0, ComplexityDaysDivisor: 1,
+				VolumetricDivisor: 4000,
+			},
 		},
 		{
 			ID:           3,
@@ -144,6 +181,13 @@ func main() {
 			DeliveryDays: 1,
 			MaxWeight:    1000.0,
 			MaxVolume:    5.0,
+			Tariff: ds.Tariff{
+				DistanceRate: 25, WeightRate: 8, VolumeRate: 200,
+				DistancePerDay: 2000, MinDeliveryDays: 1,
+				MaxLength: 3.0, MaxWidth: 1.5, MaxHeight: 1.5,
+				ComplexityFactor: 1.2, ComplexityCap: 2.0, ComplexityDaysDivisor: 2,
+				VolumetricDivisor: 6000,
+			},
 		},
 		{
 			ID:           4,
@@ -154,6 +198,13 @@ func main() {
 			DeliveryDays: 3,
 			MaxWeight:    50000.0,
 			MaxVolume:    120.0,
+			Tariff: ds.Tariff{
+				DistanceRate: 8, WeightRate: 1, VolumeRate: 30,
+				DistancePerDay: 1200, MinDeliveryDays: 2,
+				MaxLength: 20.0, MaxWidth: 3.0, MaxHeight: 3.0,
+				ComplexityFactor: 1.0, ComplexityCap: 2.0, ComplexityDaysDivisor: 1,
+				VolumetricDivisor: 3000,
+			},
 		},
 		{
 			ID:           5,
@@ -164,6 +215,13 @@ func main() {
 			DeliveryDays: 7,
 			MaxWeight:    100000.0,
 			MaxVolume:    500.0,
+			Tariff: ds.Tariff{
+				DistanceRate: 5, WeightRate: 0.5, VolumeRate: 20,
+				DistancePerDay: 500, MinDeliveryDays: 3,
+				MaxLength: 40.0, MaxWidth: 8.0, MaxHeight: 8.0,
+				ComplexityFactor: 1.0, ComplexityCap: 2.0, ComplexityDaysDivisor: 1,
+				VolumetricDivisor: 1000,
+			},
 		},
 		{
 			ID:           6,
@@ -174,6 +232,13 @@ func main() {
 			DeliveryDays: 5,
 			MaxWeight:    30000.0,
 			MaxVolume:    100.0,
+			Tariff: ds.Tariff{
+				DistanceRate: 18, WeightRate: 2.5, VolumeRate: 80,
+				DistancePerDay: 700, MinDeliveryDays: 2,
+				MaxLength: 13.6, MaxWidth: 2.5, MaxHeight: 2.7,
+				ComplexityFactor: 1.0, ComplexityCap: 2.0, ComplexityDaysDivisor: 1,
+				VolumetricDivisor: 3000,
+			},
 		},
 	}
 
@@ -184,7 +249,34 @@ func main() {
 		if err != nil {
 			// Услуга не существует, создаем
 			db.Create(&service)
+			continue
+		}
+		// Услуга уже есть, но тариф не заполнен — проставляем текущие значения,
+		// чтобы расчёт для существующих услуг не изменился
+		if existingService.DistanceRate == 0 {
+			existingService.Tariff = service.Tariff
+			db.Save(&existingService)
+		} else if existingService.VolumetricDivisor == 0 {
+			existingService.VolumetricDivisor = service.VolumetricDivisor
+			db.Save(&existingService)
+		}
+	}
+
+	// История тарифов: услугам без версий создаём первую версию из текущих значений
+	var allServices []ds.TransportService
+	db.Find(&allServices)
+	for _, service := range allServices {
+		var versions int64
+		db.Model(&ds.ServiceTariff{}).Where("transport_service_id = ?", service.ID).Count(&versions)
+		if versions > 0 {
+			continue
+		}
+		tariff := ds.SnapshotTariff(service)
+		if tariff.Version == 0 {
+			tariff.Version = 1
 		}
+		tariff.ValidFrom = service.CreatedAt
+		db.Create(&tariff)
 	}
 
 	// Создаем пример заявки
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"gorm.io/gorm"
+)
+
+// createServiceSearchIndex - полнотекстовый поиск по услугам: вычисляемый столбец tsvector
+// (название важнее описания, русская морфология) и GIN-индекс по нему
+func createServiceSearchIndex(db *gorm.DB) {
+	statements := []string{
+		`ALTER TABLE transport_services ADD COLUMN IF NOT EXISTS search_vector tsvector
+			GENERATED ALWAYS AS (
+				setweight(to_tsvector('russian', coalesce(name, '')), 'A') ||
+				setweight(to_tsvector('russian', coalesce(description, '')), 'B')
+			) STORED`,
+		`CREATE INDEX IF NOT EXISTS idx_transport_services_search_vector
+			ON transport_services USING GIN (search_vector)`,
+	}
+	for _, stmt := range statements {
+		if err := db.Exec(stmt).Error; err != nil {
+			panic("cant create transport services search index: " + err.Error())
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"rip-go-app/internal/app/config"
+	"rip-go-app/internal/app/ds"
+	"rip-go-app/internal/app/repository"
+	"rip-go-app/internal/app/scheduler"
+	"rip-go-app/internal/app/workflow"
+)
+
+// registerJobs - фоновые задачи обслуживания данных
+func registerJobs(sched *scheduler.Scheduler, repo *repository.Repository, conf *config.Config) {
+	guestTTL := time.Duration(conf.GuestSessionTTL) * time.Hour
+	rejectAfter := time.Duration(conf.AutoRejectAfterDays) * 24 * time.Hour
+	retention := time.Duration(conf.SoftDeleteRetentionDays) * 24 * time.Hour
+	// Автоматическое отклонение выполняется от имени системного модератора
+	system := workflow.Actor{UserID: ds.GetModeratorID(), Role: ds.RoleManager}
+
+	// Удаление брошенных гостевых черновиков
+	sched.Register(scheduler.Job{
+		Name:     "purge_guest_drafts",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) (string, error) {
+			removed, err := repo.CleanupGuestDrafts(guestTTL)
+			return fmt.Sprintf("removed %d guest drafts", removed), err
+		},
+	})
+
+	// Отметка истёкших расчетов
+	sched.Register(scheduler.Job{
+		Name:     "expire_quotes",
+		Interval: 15 * time.Minute,
+		Run: func(ctx context.Context) (string, error) {
+			expired, err := repo.ExpireQuotes()
+			return fmt.Sprintf("expired %d quotes", expired), err
+		},
+	})
+
+	// Перенос в услуги тарифов, дата которых наступила
+	sched.Register(scheduler.Job{
+		Name:     "apply_due_tariffs",
+		Interval: 15 * time.Minute,
+		Run: func(ctx context.Context) (string, error) {
+			applied, err := repo.ApplyDueTariffs()
+			return fmt.Sprintf("applied %d tariffs", applied), err
+		},
+	})
+
+	// Отклонение заявок, не рассмотренных модератором
+	sched.Register(scheduler.Job{
+		Name:     "auto_reject_stale_requests",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) (string, error) {
+			rejected, err := repo.AutoRejectStaleRequests(rejectAfter, system)
+			return fmt.Sprintf("rejected %d requests", rejected), err
+		},
+	})
+
+	// Окончательное удаление записей после срока хранения
+	sched.Register(scheduler.Job{
+		Name:     "purge_deleted_records",
+		Interval: 24 * time.Hour,
+		Run: func(ctx context.Context) (string, error) {
+			purged, err := repo.PurgeDeletedRecords(retention)
+			return fmt.Sprintf("purged %d requests, %d request lines, %d services",
+				purged.Requests, purged.Lines, purged.Services), err
+		},
+	})
+
+	// Удаление истёкших refresh токенов
+	sched.Register(scheduler.Job{
+		Name:     "purge_refresh_tokens",
+		Interval: 24 * time.Hour,
+		Run: func(ctx context.Context) (string, error) {
+			purged, err := repo.PurgeExpiredRefreshTokens()
+			return fmt.Sprintf("purged %d refresh tokens", purged), err
+		},
+	})
+
+	// Удаление истёкших записей об отозванных токенах и сессиях (хранилище отзыва в Postgres)
+	sched.Register(scheduler.Job{
+		Name:     "purge_revoked_tokens",
+		Interval: 24 * time.Hour,
+		Run: func(ctx context.Context) (string, error) {
+			purged, err := repo.PurgeRevokedTokens()
+			return fmt.Sprintf("purged %d revoked tokens", purged), err
+		},
+	})
+	sched.Register(scheduler.Job{
+		Name:     "purge_user_tokens",
+		Interval: 24 * time.Hour,
+		Run: func(ctx context.Context) (string, error) {
+			purged, err := repo.PurgeExpiredUserTokens()
+			return fmt.Sprintf("purged %d invite and password reset tokens", purged), err
+		},
+	})
+}
@@ -10,6 +10,7 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"io"
@@ -27,6 +28,7 @@ import (
 	"rip-go-app/internal/app/auth"
 	"rip-go-app/internal/app/service"
 	"rip-go-app/internal/app/middleware"
+	"rip-go-app/internal/app/scheduler"
 	
 	// Swagger imports
 	_ "rip-go-app/docs"
@@ -53,18 +55,35 @@ func main() {
 		logrus.Fatalf("error initializing repository: %v", err)
 	}
 
+	// Фоновые задачи (гостевые черновики, расчеты, тарифы, просроченные и удалённые заявки).
+	// Аренда в БД гарантирует, что при нескольких репликах задачу выполняет одна из них.
+	sched := scheduler.New(repo)
+	registerJobs(sched, repo, conf)
+	sched.Start(context.Background())
+
+	// Ключи подписи JWT; каталог перечитывается, чтобы подхватывать ключи для ротации
+	jwtKeys, err := auth.NewKeyring(conf.JWTKeysDir,
+		auth.TokenLifetime(conf.JWTAccessTokenExpire, conf.JWTRefreshTokenExpire))
+	if err != nil {
+		logrus.Fatalf("error loading JWT keys: %v", err)
+	}
+	jwtKeys.Watch(context.Background(), time.Minute)
+
 	// Инициализируем JWT сервис
 	jwtService := auth.NewJWTService(
-		conf.JWTSecret,
+		jwtKeys,
 		conf.JWTAccessTokenExpire,
 		conf.JWTRefreshTokenExpire,
 	)
 
+	// Хранилище отзыва токенов (выход, отзыв всех токенов пользователя)
+	revocations := newRevocationStore(conf, repo)
+
 	// Инициализируем сервис авторизации
-	authService := service.NewAuthService(repo, jwtService)
+	authService := service.NewAuthService(repo, jwtService, revocations)
 
 	// Инициализируем middleware авторизации
-	authMiddleware := middleware.NewAuthMiddleware(jwtService)
+	authMiddleware := middleware.NewAuthMiddleware(jwtService, revocations)
 
 	// Создаем хендлер
 	handler := handler.NewHandler(repo, authService, authMiddleware)
@@ -129,7 +148,7 @@ func main() {
 	})
 
 	// Регистрируем маршруты
-	registerRoutes(r, handler)
+	registerRoutes(r, handler, time.Duration(conf.GuestSessionTTL)*time.Hour)
 	
 	// Обработчик для неизвестных маршрутов (SPA fallback)
 	// Игнорируем запросы к фронтенд маршрутам, которые должны обрабатываться React Router
@@ -203,77 +222,127 @@ func main() {
 	logrus.Info("Application terminated")
 }
 
-func registerRoutes(r *gin.Engine, handler *handler.Handler) {
+func registerRoutes(r *gin.Engine, handler *handler.Handler, guestSessionTTL time.Duration) {
+	// Гостевая сессия (cookie) — ключ черновика заявки анонимного посетителя
+	guestSession := middleware.GuestSession(guestSessionTTL)
+	// Авторизация и права роли (auth.Permission); владение заявкой проверяет хендлер
+	requireAuth := handler.AuthMiddleware.RequireAuth()
+	require := handler.AuthMiddleware.RequirePermission
+
 	// HTML страницы (доменные)
 	r.GET("/", handler.GetTransportServicesPage)                                 // Каталог транспортных услуг
 	r.GET("/transport-services/:id", handler.GetTransportServicePage)            // Страница транспортной услуги
 	r.GET("/logistic-request", handler.GetLogisticRequestDetailsPage)            // Демо-страница деталей заявки
 	// Страница расчёта грузоперевозки (quote)
-	r.GET("/logistic-request/quote", handler.GetDeliveryQuotePage)
+	r.GET("/logistic-request/quote", guestSession, handler.GetDeliveryQuotePage)
 	r.POST("/logistic-request/quote", handler.PostDeliveryQuote)
 	// Алиас для совместимости
-	r.GET("/delivery-quote", handler.GetDeliveryQuotePage)
+	r.GET("/delivery-quote", guestSession, handler.GetDeliveryQuotePage)
 	r.POST("/delivery-quote", handler.PostDeliveryQuote)
 
 	// Черновик логистической заявки (guest) — бывшая "корзина"
-	r.POST("/api/logistic-requests/draft/services/:service_id", handler.AddTransportServiceToDraftLogisticRequest)
-	r.DELETE("/api/logistic-requests/draft", handler.ClearDraftLogisticRequest)
-	r.GET("/api/logistic-requests/draft", handler.GetDraftLogisticRequest)
-	r.GET("/api/logistic-requests/draft/count", handler.GetDraftLogisticRequestServiceCount)
-	r.GET("/api/logistic-requests/draft/icon", handler.GetDraftLogisticRequestIcon)
+	guestDraft := r.Group("/api/logistic-requests/draft", guestSession)
+	guestDraft.POST("/services/:service_id", handler.AddTransportServiceToDraftLogisticRequest)
+	guestDraft.DELETE("", handler.ClearDraftLogisticRequest)
+	guestDraft.GET("", handler.GetDraftLogisticRequest)
+	guestDraft.GET("/count", handler.GetDraftLogisticRequestServiceCount)
+	guestDraft.GET("/icon", handler.GetDraftLogisticRequestIcon)
 
 	// Доменные API операции под грузоперевозки
 	r.POST("/api/transport-services/search", handler.SearchTransportServices)
 	r.POST("/api/logistic-requests/quote", handler.CalculateLogisticRequestQuote)
+	r.POST("/api/logistic-requests/quote/route", handler.CalculateLogisticRequestRouteQuote)
+	r.POST("/api/logistic-requests/quote/compare", handler.CompareLogisticRequestQuotes)
+
+	// Справочник городов (автодополнение)
+	r.GET("/api/cities", handler.GetCities)
+	// Зафиксированные расчеты стоимости (только по своим заявкам, кроме менеджеров)
+	r.GET("/api/quotes/:id", requireAuth, handler.GetQuote)
+	// Публичное отслеживание груза по номеру
+	r.GET("/api/tracking/:number", handler.GetTrackingByNumber)
 
 	// CRUD JSON для транспортных услуг
-    r.GET("/api/transport-services", handler.GetTransportServices)
+    // include_deleted=true — вместе с удалёнными (только для администратора)
+    r.GET("/api/transport-services", handler.AuthMiddleware.OptionalAuth(), handler.GetTransportServices)
     r.GET("/api/transport-services/:id", handler.GetTransportService)
-    r.POST("/api/transport-services", handler.CreateTransportService)
-    r.PUT("/api/transport-services/:id", handler.UpdateTransportService)
-    r.DELETE("/api/transport-services/:id", handler.DeleteTransportService)
+    r.POST("/api/transport-services", requireAuth, require(auth.PermServicesWrite), handler.CreateTransportService)
+    r.PUT("/api/transport-services/:id", requireAuth, require(auth.PermServicesWrite), handler.UpdateTransportService)
+    r.DELETE("/api/transport-services/:id", requireAuth, require(auth.PermServicesWrite), handler.DeleteTransportService)
+    r.POST("/api/transport-services/:id/restore", requireAuth, require(auth.PermServicesWrite, auth.PermDeletedManage),
+        handler.RestoreTransportService)
+    // История и планирование тарифов услуги
+    r.GET("/api/transport-services/:id/tariffs", handler.GetServiceTariffs)
+    r.POST("/api/transport-services/:id/tariffs", requireAuth, require(auth.PermServicesWrite), handler.ScheduleServiceTariff)
 
     // Авторизация
     r.POST("/sign_up", handler.RegisterUser)
     r.POST("/login", handler.LoginUser)
-    r.POST("/logout", handler.AuthMiddleware.RequireAuth(), handler.LogoutUser)
+    r.POST("/logout", requireAuth, handler.LogoutUser)
     r.POST("/refresh", handler.RefreshToken)
+    r.POST("/password/reset", handler.ResetPassword)
+    r.GET("/.well-known/jwks.json", handler.GetJWKS)
 
     // Пользователи (требуют авторизации)›
     authGroup := r.Group("/api/users")
-    authGroup.Use(handler.AuthMiddleware.RequireAuth())
+    authGroup.Use(requireAuth)
     {
         authGroup.GET("/profile", handler.GetUserProfile)
         authGroup.PUT("/profile", handler.UpdateUserProfile)
+        authGroup.GET("/filter-presets", handler.GetFilterPresets)
+        authGroup.POST("/filter-presets", handler.SaveFilterPreset)
+        authGroup.DELETE("/filter-presets/:id", handler.DeleteFilterPreset)
+        authGroup.GET("/sessions", handler.GetUserSessions)
+        authGroup.DELETE("/sessions", handler.DeleteUserSessions)
+        authGroup.DELETE("/sessions/:id", handler.DeleteUserSessions)
     }
 
     // Логистические заявки (требуют авторизации)
     logisticGroup := r.Group("/api/logistic-requests")
-    logisticGroup.Use(handler.AuthMiddleware.RequireAuth())
+    logisticGroup.Use(requireAuth)
     {
 		// Черновик заявок авторизованного пользователя (для React UI)
 		logisticGroup.GET("/user-draft/icon", handler.GetUserDraftIcon)
-		logisticGroup.POST("/user-draft/services/:service_id", handler.AddTransportServiceToUserDraft)
-		logisticGroup.DELETE("/user-draft", handler.ClearUserDraftLogisticRequest)
+		logisticGroup.POST("/user-draft/services/:service_id", require(auth.PermRequestsCreate), handler.AddTransportServiceToUserDraft)
+		logisticGroup.DELETE("/user-draft", require(auth.PermRequestsCreate), handler.ClearUserDraftLogisticRequest)
 
-		logisticGroup.POST("", handler.CreateCargoLogisticRequest)
+		logisticGroup.POST("", require(auth.PermRequestsCreate), handler.CreateCargoLogisticRequest)
         logisticGroup.GET("", handler.GetLogisticRequests)
         logisticGroup.GET("/:id", handler.GetLogisticRequest)
+        logisticGroup.GET("/:id/history", handler.GetLogisticRequestHistory)
+        logisticGroup.GET("/:id/tracking", handler.GetLogisticRequestTracking)
         logisticGroup.DELETE("/:id", handler.DeleteLogisticRequest)
+        logisticGroup.POST("/:id/restore", require(auth.PermDeletedManage), handler.RestoreLogisticRequest)
         logisticGroup.PUT("/:id/form", handler.FormLogisticRequest)
         logisticGroup.PUT("/:id/update", handler.UpdateLogisticRequest)
+        logisticGroup.PUT("/:id/status", handler.UpdateLogisticRequestStatus)
+        logisticGroup.PUT("/:id/route", handler.SetLogisticRequestRoute)
+        logisticGroup.POST("/:id/cargo-items", handler.AddCargoItem)
+        logisticGroup.PUT("/:id/cargo-items/:item_id", handler.UpdateCargoItem)
+        logisticGroup.DELETE("/:id/cargo-items/:item_id", handler.RemoveCargoItem)
         logisticGroup.DELETE("/:id/services/:service_id", handler.RemoveServiceFromLogisticRequest)
         logisticGroup.PUT("/:id/services/:service_id", handler.UpdateLogisticRequestService)
     }
     // Завершение логистической заявки (модератор)
     moderatorLR := r.Group("/api/logistic-requests/:id")
-    moderatorLR.Use(handler.AuthMiddleware.RequireModerator())
+    moderatorLR.Use(requireAuth, require(auth.PermRequestsModerate))
     {
         moderatorLR.PUT("/complete", handler.CompleteLogisticRequest)
+        moderatorLR.POST("/tracking", handler.AddTrackingCheckpoint)
     }
 
-    // Статус логистической заявки через курсор
-    r.PUT("/api/logistic-requests/:id/status", handler.UpdateLogisticRequestStatus)
+    // Администрирование
+    adminGroup := r.Group("/api/admin")
+    adminGroup.Use(requireAuth)
+    {
+        adminGroup.GET("/jobs/runs", require(auth.PermJobsRead), handler.GetJobRuns)
+        adminGroup.POST("/users/:id/revoke-tokens", require(auth.PermUsersManage), handler.RevokeUserTokens)
+        adminGroup.GET("/users", require(auth.PermUsersManage), handler.GetUsers)
+        adminGroup.PUT("/users/:id/role", require(auth.PermUsersManage), handler.UpdateUserRole)
+        adminGroup.POST("/users/:id/disable", require(auth.PermUsersManage), handler.DisableUser)
+        adminGroup.POST("/users/:id/enable", require(auth.PermUsersManage), handler.EnableUser)
+        adminGroup.POST("/users/:id/password-reset", require(auth.PermUsersManage), handler.ForceUserPasswordReset)
+        adminGroup.POST("/invites", require(auth.PermUsersManage), handler.CreateManagerInvite)
+    }
 
     // Swagger документация
     r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
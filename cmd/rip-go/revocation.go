@@ -0,0 +1,29 @@
+package main
+
+import (
+	"github.com/sirupsen/logrus"
+	"rip-go-app/internal/app/auth"
+	"rip-go-app/internal/app/config"
+	"rip-go-app/internal/app/repository"
+)
+
+// newRevocationStore - хранилище отзыва токенов по настройке RevocationStore
+func newRevocationStore(conf *config.Config, repo *repository.Repository) auth.RevocationStore {
+	switch conf.RevocationStore {
+	case "redis":
+		redis := auth.NewRedisService(conf.RedisHost, conf.RedisPort, conf.RedisPassword, conf.RedisDB)
+		if err := redis.Ping(); err != nil {
+			logrus.Fatalf("error connecting to redis: %v", err)
+		}
+		return redis
+	case "memory":
+		// Отзыв не разделяется между экземплярами и теряется при перезапуске
+		logrus.Warn("token revocation is kept in memory")
+		return auth.NewMemoryRevocationStore()
+	case "postgres":
+		return repo
+	default:
+		logrus.Fatalf("unknown revocation store: %s", conf.RevocationStore)
+		return nil
+	}
+}
@@ -0,0 +1,408 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"rip-go-app/internal/app/auth"
+	"rip-go-app/internal/app/ds"
+	"rip-go-app/internal/app/handler"
+	"rip-go-app/internal/app/middleware"
+	"rip-go-app/internal/app/repository"
+	"rip-go-app/internal/app/service"
+)
+
+// Роли, от имени которых проверяются маршруты (anonymous — без токена)
+var policyRoles = []string{"anonymous", ds.RoleBuyer, ds.RoleManager, ds.RoleAdmin}
+
+// passes - запрос прошёл авторизацию и проверку прав: дальше код ответа определяет хендлер, но не 401/403
+const passes = 0
+
+// missingID - ID несуществующей записи для параметров маршрута в матрице прав
+const missingID = "999999999"
+
+// routePolicy - ожидаемый результат проверки прав маршрута для anonymous, buyer, manager, admin
+type routePolicy struct {
+	method string
+	route  string
+	want   [4]int
+}
+
+var (
+	authOnly  = [4]int{http.StatusUnauthorized, passes, passes, passes}
+	staffOnly = [4]int{http.StatusUnauthorized, http.StatusForbidden, passes, passes}
+	adminOnly = [4]int{http.StatusUnauthorized, http.StatusForbidden, http.StatusForbidden, passes}
+)
+
+// routePolicies - все маршруты registerRoutes, требующие авторизации
+var routePolicies = []routePolicy{
+	{http.MethodGet, "/api/quotes/:id", authOnly},
+	{http.MethodPost, "/logout", authOnly},
+
+	// Услуги: запись — services:write, восстановление — ещё и deleted:manage
+	{http.MethodPost, "/api/transport-services", staffOnly},
+	{http.MethodPut, "/api/transport-services/:id", staffOnly},
+	{http.MethodDelete, "/api/transport-services/:id", staffOnly},
+	{http.MethodPost, "/api/transport-services/:id/restore", adminOnly},
+	{http.MethodPost, "/api/transport-services/:id/tariffs", staffOnly},
+
+	// Профиль, наборы фильтров и сессии — свои у каждого пользователя
+	{http.MethodGet, "/api/users/profile", authOnly},
+	{http.MethodPut, "/api/users/profile", authOnly},
+	{http.MethodGet, "/api/users/filter-presets", authOnly},
+	{http.MethodPost, "/api/users/filter-presets", authOnly},
+	{http.MethodDelete, "/api/users/filter-presets/:id", authOnly},
+	{http.MethodGet, "/api/users/sessions", authOnly},
+	{http.MethodDelete, "/api/users/sessions", authOnly},
+	{http.MethodDelete, "/api/users/sessions/:id", authOnly},
+
+	// Заявки: владение проверяет хендлер (см. TestLogisticRequestOwnership)
+	{http.MethodGet, "/api/logistic-requests/user-draft/icon", authOnly},
+	{http.MethodPost, "/api/logistic-requests/user-draft/services/:service_id", authOnly},
+	{http.MethodDelete, "/api/logistic-requests/user-draft", authOnly},
+	{http.MethodPost, "/api/logistic-requests", authOnly},
+	{http.MethodGet, "/api/logistic-requests", authOnly},
+	{http.MethodGet, "/api/logistic-requests/:id", authOnly},
+	{http.MethodGet, "/api/logistic-requests/:id/history", authOnly},
+	{http.MethodGet, "/api/logistic-requests/:id/tracking", authOnly},
+	{http.MethodDelete, "/api/logistic-requests/:id", authOnly},
+	{http.MethodPost, "/api/logistic-requests/:id/restore", adminOnly},
+	{http.MethodPut, "/api/logistic-requests/:id/form", authOnly},
+	{http.MethodPut, "/api/logistic-requests/:id/update", authOnly},
+	{http.MethodPut, "/api/logistic-requests/:id/status", authOnly},
+	{http.MethodPut, "/api/logistic-requests/:id/route", authOnly},
+	{http.MethodPost, "/api/logistic-requests/:id/cargo-items", authOnly},
+	{http.MethodPut, "/api/logistic-requests/:id/cargo-items/:item_id", authOnly},
+	{http.MethodDelete, "/api/logistic-requests/:id/cargo-items/:item_id", authOnly},
+	{http.MethodDelete, "/api/logistic-requests/:id/services/:service_id", authOnly},
+	{http.MethodPut, "/api/logistic-requests/:id/services/:service_id", authOnly},
+	{http.MethodPut, "/api/logistic-requests/:id/complete", staffOnly},
+	{http.MethodPost, "/api/logistic-requests/:id/tracking", staffOnly},
+
+	// Администрирование
+	{http.MethodGet, "/api/admin/jobs/runs", adminOnly},
+	{http.MethodPost, "/api/admin/users/:id/revoke-tokens", adminOnly},
+	{http.MethodGet, "/api/admin/users", adminOnly},
+	{http.MethodPut, "/api/admin/users/:id/role", adminOnly},
+	{http.MethodPost, "/api/admin/users/:id/disable", adminOnly},
+	{http.MethodPost, "/api/admin/users/:id/enable", adminOnly},
+	{http.MethodPost, "/api/admin/users/:id/password-reset", adminOnly},
+	{http.MethodPost, "/api/admin/invites", adminOnly},
+}
+
+// publicRoutes - маршруты registerRoutes без авторизации
+var publicRoutes = map[string]bool{
+	"GET /":                                                  true,
+	"GET /transport-services/:id":                            true,
+	"GET /logistic-request":                                  true,
+	"GET /logistic-request/quote":                            true,
+	"POST /logistic-request/quote":                           true,
+	"GET /delivery-quote":                                    true,
+	"POST /delivery-quote":                                   true,
+	"POST /api/transport-services/search":                    true,
+	"POST /api/logistic-requests/quote":                      true,
+	"POST /api/logistic-requests/quote/route":                true,
+	"POST /api/logistic-requests/quote/compare":              true,
+	"POST /api/logistic-requests/draft/services/:service_id": true,
+	"DELETE /api/logistic-requests/draft":                    true,
+	"GET /api/logistic-requests/draft":                       true,
+	"GET /api/logistic-requests/draft/count":                 true,
+	"GET /api/logistic-requests/draft/icon":                  true,
+	"GET /api/cities":                                        true,
+	"GET /api/tracking/:number":                              true,
+	"GET /api/transport-services":                            true,
+	"GET /api/transport-services/:id":                        true,
+	"GET /api/transport-services/:id/tariffs":                true,
+	"POST /sign_up":                                          true,
+	"POST /login":                                            true,
+	"POST /refresh":                                          true,
+	"POST /password/reset":                                   true,
+	"GET /.well-known/jwks.json":                             true,
+	"GET /swagger/*any":                                      true,
+}
+
+// routeParam - параметр маршрута (:id, *any)
+var routeParam = regexp.MustCompile(`[:*][a-z_]+`)
+
+// testServer - роутер registerRoutes и выпуск access токенов для проверки маршрутов
+type testServer struct {
+	router *gin.Engine
+	jwt    *auth.JWTService
+}
+
+// newTestServer - роутер с временным ключом подписи и отзывом токенов в памяти.
+// Без БД (repo — нулевой Repository) хендлеры падают после проверки прав; паника превращается в 500.
+func newTestServer(t *testing.T, repo *repository.Repository) *testServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	keys, err := auth.NewKeyring("", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwtService := auth.NewJWTService(keys, 15, 1)
+	revocations := auth.NewMemoryRevocationStore()
+	authService := service.NewAuthService(repo, jwtService, revocations)
+	h := handler.NewHandler(repo, authService, middleware.NewAuthMiddleware(jwtService, revocations))
+
+	r := gin.New()
+	r.Use(gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, _ any) {
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}))
+	registerRoutes(r, h, time.Hour)
+	return &testServer{router: r, jwt: jwtService}
+}
+
+// do - запрос от имени пользователя (userUUID "" — без токена)
+func (s *testServer) do(t *testing.T, method, path, body, userUUID, role string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if userUUID != "" {
+		// Новый токен на каждый запрос: /logout отзывает токен, которым выполнен
+		token, err := s.jwt.GenerateAccessToken(userUUID, role, "", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+// TestRoutePoliciesCoverAllRoutes - у каждого маршрута есть строка в матрице прав или он явно публичный
+func TestRoutePoliciesCoverAllRoutes(t *testing.T) {
+	s := newTestServer(t, &repository.Repository{})
+
+	known := make(map[string]bool, len(routePolicies))
+	for _, p := range routePolicies {
+		known[p.method+" "+p.route] = true
+	}
+	for _, route := range s.router.Routes() {
+		key := route.Method + " " + route.Path
+		if !known[key] && !publicRoutes[key] {
+			t.Errorf("route %s is neither in routePolicies nor in publicRoutes", key)
+		}
+		delete(known, key)
+	}
+	for key := range known {
+		t.Errorf("routePolicies has %s, but registerRoutes does not register it", key)
+	}
+}
+
+// TestRoutePolicies - авторизация и права ролей на каждом защищённом маршруте.
+// Тело запроса — некорректный JSON, ID — несуществующие: прошедший проверку запрос ничего не меняет.
+// С TEST_DATABASE_DSN запросы выполняются от имени настоящих пользователей каждой роли.
+func TestRoutePolicies(t *testing.T) {
+	repo, users := &repository.Repository{}, map[string]ds.User{}
+	for _, role := range policyRoles[1:] {
+		users[role] = ds.User{UUID: uuid.New().String(), Role: role}
+	}
+	if db, dbRepo := openTestDB(t); db != nil {
+		repo = dbRepo
+		fx := newFixtures(t, db)
+		for _, role := range policyRoles[1:] {
+			users[role] = fx.user(role)
+		}
+	}
+	s := newTestServer(t, repo)
+
+	for _, p := range routePolicies {
+		path := routeParam.ReplaceAllString(p.route, missingID)
+		for i, role := range policyRoles {
+			t.Run(fmt.Sprintf("%s %s as %s", p.method, p.route, role), func(t *testing.T) {
+				user := users[role]
+				w := s.do(t, p.method, path, "{", user.UUID, user.Role)
+				switch want := p.want[i]; {
+				case want == passes && (w.Code == http.StatusUnauthorized || w.Code == http.StatusForbidden):
+					t.Errorf("got %d, want the request to pass the policy: %s", w.Code, w.Body)
+				case want != passes && w.Code != want:
+					t.Errorf("got %d, want %d: %s", w.Code, want, w.Body)
+				}
+			})
+		}
+	}
+}
+
+// TestLogisticRequestOwnership - доступ к чужим заявкам: без права requests:read:any / requests:write:any
+// заявка (и её расчет) не существует для пользователя — 404, а не 403. Нужна БД (TEST_DATABASE_DSN).
+func TestLogisticRequestOwnership(t *testing.T) {
+	db, repo := openTestDB(t)
+	if db == nil {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+	fx := newFixtures(t, db)
+	s := newTestServer(t, repo)
+
+	owner, other := fx.user(ds.RoleBuyer), fx.user("other_buyer")
+	actors := []ds.User{owner, other, fx.user(ds.RoleManager), fx.user(ds.RoleAdmin)}
+	draft, formed := fx.draft.ID, fx.formed.ID
+	line := func(id int) string {
+		return fmt.Sprintf("/api/logistic-requests/%d/services/%d", id, fx.service.ID)
+	}
+
+	// Ожидаемые коды для владельца, другого покупателя, менеджера и администратора
+	read := [4]int{http.StatusOK, http.StatusNotFound, http.StatusOK, http.StatusOK}
+	write := [4]int{http.StatusOK, http.StatusNotFound, http.StatusNotFound, http.StatusOK}
+	locked := [4]int{http.StatusConflict, http.StatusNotFound, http.StatusNotFound, http.StatusConflict}
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		want   [4]int
+	}{
+		{http.MethodGet, fmt.Sprintf("/api/logistic-requests/%d", draft), "", read},
+		{http.MethodGet, fmt.Sprintf("/api/logistic-requests/%d/history", draft), "", read},
+		{http.MethodGet, fmt.Sprintf("/api/logistic-requests/%d/tracking", draft), "", read},
+		{http.MethodGet, fmt.Sprintf("/api/logistic-requests/%d", formed), "", read},
+		{http.MethodGet, fmt.Sprintf("/api/quotes/%d", fx.quote.ID), "", read},
+		{http.MethodPut, fmt.Sprintf("/api/logistic-requests/%d/update", draft), `{}`, write},
+		{http.MethodPut, line(draft), `{"quantity": 1}`, write},
+		// Строки сформированной заявки не меняются: цена уже зафиксирована
+		{http.MethodPut, line(formed), `{"quantity": 2}`, locked},
+		{http.MethodDelete, line(formed), "", locked},
+	}
+	names := []string{"owner", "other buyer", "manager", "admin"}
+	for _, tt := range tests {
+		for i, actor := range actors {
+			t.Run(fmt.Sprintf("%s %s as %s", tt.method, tt.path, names[i]), func(t *testing.T) {
+				w := s.do(t, tt.method, tt.path, tt.body, actor.UUID, actor.Role)
+				if w.Code != tt.want[i] {
+					t.Errorf("got %d, want %d: %s", w.Code, tt.want[i], w.Body)
+				}
+			})
+		}
+	}
+}
+
+// openTestDB - подключение к тестовой БД (схема создается cmd/migrate); nil, если TEST_DATABASE_DSN не задан
+func openTestDB(t *testing.T) (*gorm.DB, *repository.Repository) {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		return nil, nil
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := repository.New(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, repo
+}
+
+// fixtures - пользователи всех ролей, услуга, черновик и сформированная заявка покупателя с расчетом.
+// Всё созданное (в том числе хендлерами) удаляется по завершении теста.
+type fixtures struct {
+	users   map[string]ds.User
+	service ds.TransportService
+	draft   ds.LogisticRequest
+	formed  ds.LogisticRequest
+	quote   ds.Quote
+}
+
+func newFixtures(t *testing.T, db *gorm.DB) *fixtures {
+	t.Helper()
+	fx := &fixtures{users: map[string]ds.User{}}
+	suffix := uuid.New().String()[:8]
+	var userIDs []int
+	t.Cleanup(func() { cleanupFixtures(t, db, userIDs, fx.service.ID) })
+
+	roles := map[string]string{
+		ds.RoleBuyer: ds.RoleBuyer, "other_buyer": ds.RoleBuyer, ds.RoleManager: ds.RoleManager, ds.RoleAdmin: ds.RoleAdmin,
+	}
+	for name, role := range roles {
+		login := fmt.Sprintf("policy-%s-%s", suffix, name)
+		user := ds.User{
+			UUID: uuid.New().String(), Login: login, Email: login + "@example.test",
+			Password: "-", Name: login, Role: role,
+		}
+		mustCreate(t, db, &user)
+		userIDs = append(userIDs, user.ID)
+		fx.users[name] = user
+	}
+
+	fx.service = ds.TransportService{
+		Name: "policy-" + suffix, Price: 1000, DeliveryDays: 1, MaxWeight: 10000, MaxVolume: 100,
+	}
+	mustCreate(t, db, &fx.service)
+
+	owner := fx.users[ds.RoleBuyer].ID
+	now := time.Now()
+	fx.draft = ds.LogisticRequest{CreatorID: owner, Status: ds.StatusDraft, IsDraft: true}
+	fx.formed = ds.LogisticRequest{
+		CreatorID: owner, Status: ds.StatusFormed, FromCity: "Москва", ToCity: "Казань",
+		Weight: 10, Length: 1, Width: 1, Height: 1, Volume: 1, FormedAt: &now,
+	}
+	for _, order := range []*ds.LogisticRequest{&fx.draft, &fx.formed} {
+		mustCreate(t, db, order)
+		mustCreate(t, db, &ds.LogisticRequestService{
+			LogisticRequestID: order.ID, TransportServiceID: fx.service.ID, Quantity: 1,
+		})
+	}
+
+	fx.quote = ds.Quote{
+		LogisticRequestID: fx.formed.ID, FromCity: fx.formed.FromCity, ToCity: fx.formed.ToCity,
+		Weight: fx.formed.Weight, Volume: fx.formed.Volume, TotalCost: 1000, TotalDays: 1,
+		ValidUntil: now.Add(time.Hour),
+	}
+	mustCreate(t, db, &fx.quote)
+	return fx
+}
+
+// user - пользователь фикстуры по имени: роль или other_buyer
+func (fx *fixtures) user(name string) ds.User {
+	return fx.users[name]
+}
+
+func mustCreate(t *testing.T, db *gorm.DB, value interface{}) {
+	t.Helper()
+	if err := db.Omit(clause.Associations).Create(value).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// cleanupFixtures - удаление пользователей фикстуры, их заявок со всеми связанными записями и услуги
+func cleanupFixtures(t *testing.T, db *gorm.DB, userIDs []int, serviceID int) {
+	if len(userIDs) == 0 {
+		return
+	}
+	requests := "SELECT id FROM logistic_requests WHERE creator_id IN @users"
+	statements := []string{
+		"DELETE FROM logistic_request_events WHERE logistic_request_id IN (" + requests + ")",
+		"DELETE FROM quote_lines WHERE quote_id IN (SELECT id FROM quotes WHERE logistic_request_id IN (" + requests + "))",
+		"DELETE FROM quotes WHERE logistic_request_id IN (" + requests + ")",
+		"DELETE FROM tracking_checkpoints WHERE logistic_request_id IN (" + requests + ")",
+		"DELETE FROM cargo_items WHERE logistic_request_id IN (" + requests + ")",
+		"DELETE FROM logistic_request_legs WHERE logistic_request_id IN (" + requests + ")",
+		"DELETE FROM logistic_request_services WHERE logistic_request_id IN (" + requests + ")",
+		"DELETE FROM logistic_requests WHERE creator_id IN @users",
+		"DELETE FROM filter_presets WHERE user_id IN @users",
+		"DELETE FROM refresh_tokens WHERE user_id IN @users",
+		"DELETE FROM user_tokens WHERE user_id IN @users OR created_by_id IN @users",
+		"DELETE FROM users WHERE id IN @users",
+		"DELETE FROM transport_services WHERE id = @service",
+	}
+	for _, stmt := range statements {
+		err := db.Exec(stmt, map[string]interface{}{"users": userIDs, "service": serviceID}).Error
+		if err != nil {
+			t.Errorf("cleanup: %v", err)
+		}
+	}
+}
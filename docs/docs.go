@@ -496,6 +496,10 @@ const docTemplate = `{
                 "email": {
                     "type": "string"
                 },
+                "invite_token": {
+                    "description": "Приглашение менеджера; без него регистрируется покупатель",
+                    "type": "string"
+                },
                 "login": {
                     "type": "string"
                 },
@@ -508,9 +512,6 @@ const docTemplate = `{
                 },
                 "phone": {
                     "type": "string"
-                },
-                "role": {
-                    "type": "string"
                 }
             }
         }
@@ -31,14 +31,20 @@ func StartServer() {
 		logrus.Fatalf("error initializing repository: %v", err)
 	}
 
+	jwtKeys, err := auth.NewKeyring(conf.JWTKeysDir,
+		auth.TokenLifetime(conf.JWTAccessTokenExpire, conf.JWTRefreshTokenExpire))
+	if err != nil {
+		logrus.Fatalf("error loading JWT keys: %v", err)
+	}
+
 	jwtService := auth.NewJWTService(
-		conf.JWTSecret,
+		jwtKeys,
 		conf.JWTAccessTokenExpire,
 		conf.JWTRefreshTokenExpire,
 	)
 
-	authService := service.NewAuthService(repo, jwtService)
-	authMiddleware := middleware.NewAuthMiddleware(jwtService)
+	authService := service.NewAuthService(repo, jwtService, repo)
+	authMiddleware := middleware.NewAuthMiddleware(jwtService, repo)
 
 	h := handler.NewHandler(repo, authService, authMiddleware)
 
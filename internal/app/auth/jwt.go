@@ -13,31 +13,63 @@ type JWTClaims struct {
 	UserUUID string `json:"user_uuid"`
 	Role     string `json:"role"`
 	Type     string `json:"type"` // "access" или "refresh"
+	// SessionID - семейство refresh токенов (сессия устройства), к которому относится токен
+	SessionID string `json:"sid,omitempty"`
+	// Generation - поколение токенов пользователя на момент выдачи (см. RevocationStore)
+	Generation int `json:"gen"`
 	jwt.RegisteredClaims
 }
 
-// JWTService - сервис для работы с JWT
+// JWTService - сервис для работы с JWT (подпись RS256/EdDSA ключами из Keyring, kid в заголовке)
 type JWTService struct {
-	secretKey              string
+	keys                   *Keyring
 	accessTokenExpiration  time.Duration
 	refreshTokenExpiration time.Duration
 }
 
 // NewJWTService - создание нового JWT сервиса
-func NewJWTService(secretKey string, accessExpMin, refreshExpDays int) *JWTService {
+func NewJWTService(keys *Keyring, accessExpMin, refreshExpDays int) *JWTService {
 	return &JWTService{
-		secretKey:              secretKey,
+		keys:                   keys,
 		accessTokenExpiration:  time.Duration(accessExpMin) * time.Minute,
 		refreshTokenExpiration: time.Duration(refreshExpDays) * 24 * time.Hour,
 	}
 }
 
-// GenerateAccessToken - генерация access токена
-func (j *JWTService) GenerateAccessToken(userUUID, role string) (string, error) {
+// TokenLifetime - наибольший срок жизни токена (сколько замененный ключ нужен для проверки)
+func TokenLifetime(accessExpMin, refreshExpDays int) time.Duration {
+	return time.Duration(accessExpMin)*time.Minute + time.Duration(refreshExpDays)*24*time.Hour
+}
+
+// AccessTokenExpiration - срок жизни access токена из конфигурации
+func (j *JWTService) AccessTokenExpiration() time.Duration {
+	return j.accessTokenExpiration
+}
+
+// sign - подпись claims действующим ключом
+func (j *JWTService) sign(claims JWTClaims) (string, error) {
+	key, err := j.keys.Signing(time.Now())
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.private)
+}
+
+// JWKS - открытые ключи для проверки токенов
+func (j *JWTService) JWKS() JWKS {
+	return j.keys.JWKS(time.Now())
+}
+
+// GenerateAccessToken - генерация access токена сессии sessionID поколения generation
+func (j *JWTService) GenerateAccessToken(userUUID, role, sessionID string, generation int) (string, error) {
 	claims := JWTClaims{
-		UserUUID: userUUID,
-		Role:     role,
-		Type:     "access",
+		UserUUID:   userUUID,
+		Role:       role,
+		Type:       "access",
+		SessionID:  sessionID,
+		Generation: generation,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.accessTokenExpiration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -46,16 +78,18 @@ func (j *JWTService) GenerateAccessToken(userUUID, role string) (string, error)
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(j.secretKey))
+	return j.sign(claims)
 }
 
-// GenerateRefreshToken - генерация refresh токена
-func (j *JWTService) GenerateRefreshToken(userUUID, role string) (string, error) {
+// GenerateRefreshToken - генерация refresh токена сессии sessionID поколения generation.
+// Возвращает и claims: jti и срок действия нужны для учета токена на сервере.
+func (j *JWTService) GenerateRefreshToken(userUUID, role, sessionID string, generation int) (string, *JWTClaims, error) {
 	claims := JWTClaims{
-		UserUUID: userUUID,
-		Role:     role,
-		Type:     "refresh",
+		UserUUID:   userUUID,
+		Role:       role,
+		Type:       "refresh",
+		SessionID:  sessionID,
+		Generation: generation,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.refreshTokenExpiration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -64,18 +98,26 @@ func (j *JWTService) GenerateRefreshToken(userUUID, role string) (string, error)
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(j.secretKey))
+	token, err := j.sign(claims)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, &claims, nil
 }
 
 // ValidateToken - валидация токена
 func (j *JWTService) ValidateToken(tokenString string) (*JWTClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := j.keys.Verification(kid, time.Now())
+		if !ok {
+			return nil, errors.New("unknown signing key")
+		}
+		if token.Method.Alg() != key.Algorithm {
 			return nil, errors.New("unexpected signing method")
 		}
-		return []byte(j.secretKey), nil
-	})
+		return key.public, nil
+	}, jwt.WithValidMethods([]string{AlgRS256, AlgEdDSA}))
 
 	if err != nil {
 		return nil, err
@@ -88,30 +130,6 @@ func (j *JWTService) ValidateToken(tokenString string) (*JWTClaims, error) {
 	return nil, errors.New("invalid token")
 }
 
-// RefreshTokenPair - обновление пары токенов
-func (j *JWTService) RefreshTokenPair(refreshToken string) (string, string, error) {
-	claims, err := j.ValidateToken(refreshToken)
-	if err != nil {
-		return "", "", err
-	}
-
-	if claims.Type != "refresh" {
-		return "", "", errors.New("invalid token type")
-	}
-
-	accessToken, err := j.GenerateAccessToken(claims.UserUUID, claims.Role)
-	if err != nil {
-		return "", "", err
-	}
-
-	newRefreshToken, err := j.GenerateRefreshToken(claims.UserUUID, claims.Role)
-	if err != nil {
-		return "", "", err
-	}
-
-	return accessToken, newRefreshToken, nil
-}
-
 // GetTokenExpiration - получение времени истечения токена
 func (j *JWTService) GetTokenExpiration(tokenString string) (time.Duration, error) {
 	claims, err := j.ValidateToken(tokenString)
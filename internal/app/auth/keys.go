@@ -0,0 +1,299 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Алгоритмы подписи токенов (определяются типом ключа)
+const (
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// Суффиксы файлов ключей в каталоге: закрытый ключ подписи и открытый ключ только для проверки
+const (
+	privateKeySuffix = ".pem"
+	publicKeySuffix  = ".pub.pem"
+)
+
+// SigningKey - ключ подписи токенов, kid - имя файла ключа.
+// Ключ с датой в начале kid (2006-01-02-...) начинает подписывать токены с этой даты.
+type SigningKey struct {
+	ID         string
+	Algorithm  string
+	ActiveFrom time.Time
+	private    crypto.Signer // nil - ключ только для проверки
+	public     crypto.PublicKey
+}
+
+// Keyring - набор ключей подписи. Подписывает последний вступивший в действие закрытый ключ;
+// замененный ключ остаётся действительным для проверки ещё retention (пока живут выданные им токены).
+// Ключи с будущей датой публикуются в JWKS заранее.
+type Keyring struct {
+	mu        sync.RWMutex
+	dir       string
+	envKey    *SigningKey
+	keys      []*SigningKey // по ActiveFrom, затем по ID
+	retention time.Duration
+}
+
+// NewKeyring - загрузка ключей из каталога dir и переменных окружения JWT_PRIVATE_KEY (PEM) и JWT_KEY_ID.
+// Без ключей создается временный Ed25519 ключ (токены не переживут перезапуск).
+func NewKeyring(dir string, retention time.Duration) (*Keyring, error) {
+	k := &Keyring{dir: dir, retention: retention}
+
+	if data := os.Getenv("JWT_PRIVATE_KEY"); data != "" {
+		kid := os.Getenv("JWT_KEY_ID")
+		if kid == "" {
+			kid = "env"
+		}
+		key, err := parseKey(kid, []byte(data), false)
+		if err != nil {
+			return nil, fmt.Errorf("JWT_PRIVATE_KEY: %w", err)
+		}
+		k.envKey = key
+	}
+
+	if err := k.Reload(); err != nil {
+		return nil, err
+	}
+	if len(k.keys) == 0 {
+		logrus.Warn("no JWT signing keys configured, using an ephemeral Ed25519 key")
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		kid := fmt.Sprintf("ephemeral-%d", time.Now().Unix())
+		k.envKey = &SigningKey{ID: kid, Algorithm: AlgEdDSA, private: private, public: private.Public()}
+		k.keys = []*SigningKey{k.envKey}
+	}
+	return k, nil
+}
+
+// Reload - перечитывание каталога ключей (новые ключи для ротации, удалённые — больше не действуют)
+func (k *Keyring) Reload() error {
+	keys, err := loadKeyDir(k.dir)
+	if err != nil {
+		return err
+	}
+	if k.envKey != nil {
+		keys = append(keys, k.envKey)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].ActiveFrom.Equal(keys[j].ActiveFrom) {
+			return keys[i].ActiveFrom.Before(keys[j].ActiveFrom)
+		}
+		return keys[i].ID < keys[j].ID
+	})
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if len(keys) == 0 && len(k.keys) > 0 {
+		// Все ключи пропали из каталога — оставляем прежние, чтобы не разлогинить всех
+		return errors.New("в каталоге не осталось ключей подписи")
+	}
+	k.keys = keys
+	return nil
+}
+
+// Watch - периодическое перечитывание каталога ключей до отмены ctx
+func (k *Keyring) Watch(ctx context.Context, interval time.Duration) {
+	if k.dir == "" {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := k.Reload(); err != nil {
+					logrus.Errorf("failed to reload JWT keys: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Signing - ключ, которым подписываются токены в момент now
+func (k *Keyring) Signing(now time.Time) (*SigningKey, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	for i := len(k.keys) - 1; i >= 0; i-- {
+		if key := k.keys[i]; key.private != nil && !key.ActiveFrom.After(now) {
+			return key, nil
+		}
+	}
+	return nil, errors.New("нет действующего ключа подписи")
+}
+
+// Verification - ключ kid для проверки подписи токена в момент now
+func (k *Keyring) Verification(kid string, now time.Time) (*SigningKey, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	for i, key := range k.keys {
+		if key.ID == kid {
+			return key, !key.ActiveFrom.After(now) && !k.retired(i, now)
+		}
+	}
+	return nil, false
+}
+
+// retired - закрытый ключ заменен следующим ключом подписи больше retention назад.
+// Открытые ключи (.pub.pem) действуют, пока лежат в каталоге.
+func (k *Keyring) retired(i int, now time.Time) bool {
+	if k.keys[i].private == nil {
+		return false
+	}
+	for _, next := range k.keys[i+1:] {
+		if next.private != nil && !next.ActiveFrom.After(now) {
+			return next.ActiveFrom.Add(k.retention).Before(now)
+		}
+	}
+	return false
+}
+
+// JWK - открытый ключ в формате JSON Web Key
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`   // RSA: модуль
+	E         string `json:"e,omitempty"`   // RSA: экспонента
+	Curve     string `json:"crv,omitempty"` // OKP: кривая
+	X         string `json:"x,omitempty"`   // OKP: открытый ключ
+}
+
+// JWKS - набор открытых ключей для проверки токенов другими сервисами
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS - действующие и будущие открытые ключи
+func (k *Keyring) JWKS(now time.Time) JWKS {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	set := JWKS{Keys: []JWK{}}
+	for i, key := range k.keys {
+		if k.retired(i, now) {
+			continue
+		}
+		jwk := JWK{KeyID: key.ID, Use: "sig", Algorithm: key.Algorithm}
+		switch public := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// loadKeyDir - ключи из файлов <kid>.pem (закрытые) и <kid>.pub.pem (открытые); нет каталога — нет ключей
+func loadKeyDir(dir string) ([]*SigningKey, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []*SigningKey
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, privateKeySuffix) {
+			continue
+		}
+		public := strings.HasSuffix(name, publicKeySuffix)
+		kid := strings.TrimSuffix(name, privateKeySuffix)
+		if public {
+			kid = strings.TrimSuffix(name, publicKeySuffix)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		key, err := parseKey(kid, data, public)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// parseKey - ключ из PEM: закрытый PKCS#8 или PKCS#1 (RSA), открытый PKIX
+func parseKey(kid string, data []byte, public bool) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("не найден PEM блок")
+	}
+
+	key := &SigningKey{ID: kid}
+	if len(kid) >= len("2006-01-02") {
+		if activeFrom, err := time.Parse("2006-01-02", kid[:len("2006-01-02")]); err == nil {
+			key.ActiveFrom = activeFrom
+		}
+	}
+
+	var parsed interface{}
+	var err error
+	switch {
+	case public:
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case block.Type == "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch parsed := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Algorithm, key.private, key.public = AlgRS256, parsed, parsed.Public()
+	case ed25519.PrivateKey:
+		key.Algorithm, key.private, key.public = AlgEdDSA, parsed, parsed.Public()
+	case *rsa.PublicKey:
+		key.Algorithm, key.public = AlgRS256, parsed
+	case ed25519.PublicKey:
+		key.Algorithm, key.public = AlgEdDSA, parsed
+	default:
+		return nil, errors.New("поддерживаются только ключи RSA и Ed25519")
+	}
+	if rsaKey, ok := key.public.(*rsa.PublicKey); ok && rsaKey.N.BitLen() < 2048 {
+		return nil, errors.New("RSA ключ должен быть не короче 2048 бит")
+	}
+	return key, nil
+}
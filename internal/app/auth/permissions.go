@@ -0,0 +1,45 @@
+package auth
+
+import "rip-go-app/internal/app/ds"
+
+// Permission - право на действие в API
+type Permission string
+
+// Права
+const (
+	PermServicesWrite    Permission = "services:write"     // создание, изменение, удаление услуг и тарифов
+	PermRequestsCreate   Permission = "requests:create"    // свои черновики и заявки
+	PermRequestsReadAny  Permission = "requests:read:any"  // просмотр чужих заявок
+	PermRequestsWriteAny Permission = "requests:write:any" // изменение чужих черновиков
+	PermRequestsModerate Permission = "requests:moderate"  // завершение, отклонение, отметки отслеживания
+	PermDeletedManage    Permission = "deleted:manage"     // просмотр и восстановление удалённых записей
+	PermJobsRead         Permission = "jobs:read"          // история фоновых задач
+	PermUsersManage      Permission = "users:manage"       // управление пользователями и их токенами
+)
+
+// rolePermissions - права ролей
+var rolePermissions = map[string][]Permission{
+	ds.RoleBuyer: {PermRequestsCreate},
+	ds.RoleManager: {
+		PermRequestsCreate, PermRequestsReadAny, PermRequestsModerate, PermServicesWrite,
+	},
+	ds.RoleAdmin: {
+		PermRequestsCreate, PermRequestsReadAny, PermRequestsWriteAny, PermRequestsModerate,
+		PermServicesWrite, PermDeletedManage, PermJobsRead, PermUsersManage,
+	},
+}
+
+// Permissions - права роли
+func Permissions(role string) []Permission {
+	return rolePermissions[role]
+}
+
+// HasPermission - есть ли у роли право
+func HasPermission(role string, permission Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -3,12 +3,13 @@ package auth
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 )
 
-// RedisService - сервис для работы с Redis
+// RedisService - сервис для работы с Redis (в том числе хранилище отзыва токенов, RevocationStore)
 type RedisService struct {
 	client *redis.Client
 	ctx    context.Context
@@ -28,15 +29,15 @@ func NewRedisService(host string, port int, password string, db int) *RedisServi
 	}
 }
 
-// WriteJWTToBlacklist - добавление JWT токена в blacklist
-func (r *RedisService) WriteJWTToBlacklist(token string, expiration time.Duration) error {
-	key := fmt.Sprintf("blacklist:jwt:%s", token)
+// WriteJWTToBlacklist - добавление JWT токена (по jti) в blacklist
+func (r *RedisService) WriteJWTToBlacklist(jti string, expiration time.Duration) error {
+	key := fmt.Sprintf("blacklist:jwt:%s", jti)
 	return r.client.Set(r.ctx, key, "1", expiration).Err()
 }
 
-// CheckJWTInBlacklist - проверка наличия JWT токена в blacklist
-func (r *RedisService) CheckJWTInBlacklist(token string) (bool, error) {
-	key := fmt.Sprintf("blacklist:jwt:%s", token)
+// CheckJWTInBlacklist - проверка наличия JWT токена (по jti) в blacklist
+func (r *RedisService) CheckJWTInBlacklist(jti string) (bool, error) {
+	key := fmt.Sprintf("blacklist:jwt:%s", jti)
 	result := r.client.Get(r.ctx, key)
 	if result.Err() == redis.Nil {
 		return false, nil
@@ -47,6 +48,49 @@ func (r *RedisService) CheckJWTInBlacklist(token string) (bool, error) {
 	return true, nil
 }
 
+// RevokeToken - отзыв токена до истечения его срока
+func (r *RedisService) RevokeToken(jti string, ttl time.Duration) error {
+	return r.WriteJWTToBlacklist(jti, ttl)
+}
+
+// IsTokenRevoked - отозван ли токен
+func (r *RedisService) IsTokenRevoked(jti string) (bool, error) {
+	return r.CheckJWTInBlacklist(jti)
+}
+
+// RevokeSessionTokens - отзыв всех токенов сессии на ttl
+func (r *RedisService) RevokeSessionTokens(sessionID string, ttl time.Duration) error {
+	key := fmt.Sprintf("revoked:session:%s", sessionID)
+	return r.client.Set(r.ctx, key, "1", ttl).Err()
+}
+
+// IsSessionRevoked - завершена ли сессия
+func (r *RedisService) IsSessionRevoked(sessionID string) (bool, error) {
+	key := fmt.Sprintf("revoked:session:%s", sessionID)
+	count, err := r.client.Exists(r.ctx, key).Result()
+	return count > 0, err
+}
+
+// TokenGeneration - текущее поколение токенов пользователя
+func (r *RedisService) TokenGeneration(userUUID string) (int, error) {
+	key := fmt.Sprintf("generation:user:%s", userUUID)
+	result, err := r.client.Get(r.ctx, key).Result()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(result)
+}
+
+// BumpTokenGeneration - новое поколение токенов пользователя
+func (r *RedisService) BumpTokenGeneration(userUUID string) (int, error) {
+	key := fmt.Sprintf("generation:user:%s", userUUID)
+	generation, err := r.client.Incr(r.ctx, key).Result()
+	return int(generation), err
+}
+
 // StoreUserSession - сохранение сессии пользователя
 func (r *RedisService) StoreUserSession(userUUID string, sessionData map[string]interface{}, expiration time.Duration) error {
 	key := fmt.Sprintf("session:user:%s", userUUID)
@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// RevocationStore - хранилище отзыва токенов: отозванные jti (до истечения срока токена),
+// завершённые сессии (sid) и поколение токенов пользователя. Токены с поколением меньше текущего недействительны.
+type RevocationStore interface {
+	RevokeToken(jti string, ttl time.Duration) error
+	IsTokenRevoked(jti string) (bool, error)
+	RevokeSessionTokens(sessionID string, ttl time.Duration) error
+	IsSessionRevoked(sessionID string) (bool, error)
+	TokenGeneration(userUUID string) (int, error)
+	BumpTokenGeneration(userUUID string) (int, error)
+}
+
+// IsRevoked - отозван ли токен: по jti, завершением его сессии или сменой поколения токенов пользователя
+func IsRevoked(store RevocationStore, claims *JWTClaims) (bool, error) {
+	revoked, err := store.IsTokenRevoked(claims.ID)
+	if err != nil || revoked {
+		return revoked, err
+	}
+	if claims.SessionID != "" {
+		revoked, err := store.IsSessionRevoked(claims.SessionID)
+		if err != nil || revoked {
+			return revoked, err
+		}
+	}
+	generation, err := store.TokenGeneration(claims.UserUUID)
+	if err != nil {
+		return false, err
+	}
+	return claims.Generation < generation, nil
+}
+
+// MemoryRevocationStore - хранилище отзыва в памяти процесса (для одного экземпляра и тестов)
+type MemoryRevocationStore struct {
+	mu          sync.Mutex
+	revoked     map[string]time.Time // jti → истечение записи
+	sessions    map[string]time.Time // sid → истечение записи
+	generations map[string]int
+}
+
+// NewMemoryRevocationStore - создание хранилища отзыва в памяти
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{
+		revoked:     make(map[string]time.Time),
+		sessions:    make(map[string]time.Time),
+		generations: make(map[string]int),
+	}
+}
+
+// RevokeToken - отзыв токена на ttl; заодно удаляются истёкшие записи
+func (m *MemoryRevocationStore) RevokeToken(jti string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	for id, expires := range m.revoked {
+		if !expires.After(now) {
+			delete(m.revoked, id)
+		}
+	}
+	m.revoked[jti] = now.Add(ttl)
+	return nil
+}
+
+// RevokeSessionTokens - отзыв всех токенов сессии на ttl
+func (m *MemoryRevocationStore) RevokeSessionTokens(sessionID string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	for id, expires := range m.sessions {
+		if !expires.After(now) {
+			delete(m.sessions, id)
+		}
+	}
+	m.sessions[sessionID] = now.Add(ttl)
+	return nil
+}
+
+// IsSessionRevoked - завершена ли сессия
+func (m *MemoryRevocationStore) IsSessionRevoked(sessionID string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	expires, ok := m.sessions[sessionID]
+	return ok && expires.After(time.Now()), nil
+}
+
+// IsTokenRevoked - отозван ли токен
+func (m *MemoryRevocationStore) IsTokenRevoked(jti string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	expires, ok := m.revoked[jti]
+	return ok && expires.After(time.Now()), nil
+}
+
+// TokenGeneration - текущее поколение токенов пользователя
+func (m *MemoryRevocationStore) TokenGeneration(userUUID string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.generations[userUUID], nil
+}
+
+// BumpTokenGeneration - новое поколение токенов пользователя (все выданные ранее недействительны)
+func (m *MemoryRevocationStore) BumpTokenGeneration(userUUID string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.generations[userUUID]++
+	return m.generations[userUUID], nil
+}
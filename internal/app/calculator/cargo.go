@@ -0,0 +1,113 @@
+package calculator
+
+import (
+	"fmt"
+	"math"
+
+	"rip-go-app/internal/app/ds"
+)
+
+// CargoPiece - грузовое место: габариты и вес одного места и количество таких мест
+type CargoPiece struct {
+	Quantity int
+	Length   float64
+	Width    float64
+	Height   float64
+	Weight   float64
+}
+
+// count - количество мест (не меньше одного)
+func (p CargoPiece) count() float64 {
+	if p.Quantity < 1 {
+		return 1
+	}
+	return float64(p.Quantity)
+}
+
+// TotalWeight - общий вес груза, кг
+func TotalWeight(pieces []CargoPiece) float64 {
+	total := 0.0
+	for _, p := range pieces {
+		total += p.Weight * p.count()
+	}
+	return total
+}
+
+// TotalVolume - общий объем груза, м³
+func TotalVolume(pieces []CargoPiece) float64 {
+	total := 0.0
+	for _, p := range pieces {
+		total += p.Length * p.Width * p.Height * p.count()
+	}
+	return total
+}
+
+// MaxPieceDimensions - наибольшие длина, ширина и высота среди мест груза
+func MaxPieceDimensions(pieces []CargoPiece) (length, width, height float64) {
+	for _, p := range pieces {
+		length = math.Max(length, p.Length)
+		width = math.Max(width, p.Width)
+		height = math.Max(height, p.Height)
+	}
+	return length, width, height
+}
+
+// CheckCargoPiece - проверка одного места по максимальным габаритам и весу транспорта
+func CheckCargoPiece(service ds.TransportService, piece CargoPiece) error {
+	if piece.Weight > service.MaxWeight {
+		return fmt.Errorf("вес места превышает допустимый для услуги \"%s\"", service.Name)
+	}
+	dims := TariffOf(service)
+	if piece.Length > dims.MaxLength || piece.Width > dims.MaxWidth || piece.Height > dims.MaxHeight {
+		return fmt.Errorf("габариты места превышают допустимые для услуги \"%s\" (%.1f×%.1f×%.1f м)",
+			service.Name, dims.MaxLength, dims.MaxWidth, dims.MaxHeight)
+	}
+	return nil
+}
+
+// CargoConstraint - ограничение транспорта, которому не соответствует груз
+type CargoConstraint struct {
+	Constraint string  `json:"constraint"` // max_weight, max_volume, max_length, max_width, max_height
+	Limit      float64 `json:"limit"`
+	Value      float64 `json:"value"`
+	Message    string  `json:"message"`
+}
+
+// CheckCargoFit - все ограничения транспорта, которым не соответствует груз (пусто — груз подходит).
+// Вес и объем сравниваются по всему грузу, габариты — по наибольшим среди мест.
+func CheckCargoFit(service ds.TransportService, pieces []CargoPiece) []CargoConstraint {
+	dims := TariffOf(service)
+	length, width, height := MaxPieceDimensions(pieces)
+	checks := []CargoConstraint{
+		{"max_weight", service.MaxWeight, TotalWeight(pieces), "вес груза превышает грузоподъёмность"},
+		{"max_volume", service.MaxVolume, TotalVolume(pieces), "объем груза превышает вместимость"},
+		{"max_length", dims.MaxLength, length, "длина места превышает допустимую"},
+		{"max_width", dims.MaxWidth, width, "ширина места превышает допустимую"},
+		{"max_height", dims.MaxHeight, height, "высота места превышает допустимую"},
+	}
+	var failed []CargoConstraint
+	for _, c := range checks {
+		if c.Value > c.Limit {
+			failed = append(failed, c)
+		}
+	}
+	return failed
+}
+
+// CalculateCargoDelivery - расчет доставки груза из нескольких мест.
+// Каждое место проверяется по габаритам транспорта, вес и объем суммируются по всем местам.
+func (dc *DeliveryCalculator) CalculateCargoDelivery(service ds.TransportService, fromCity, toCity string, pieces []CargoPiece) DeliveryResult {
+	if len(pieces) == 0 {
+		return DeliveryResult{IsValid: false, ErrorMessage: "Не указаны параметры груза"}
+	}
+	for i, piece := range pieces {
+		if err := CheckCargoPiece(service, piece); err != nil {
+			msg := "Груз не соответствует ограничениям выбранного типа транспорта"
+			if len(pieces) > 1 {
+				msg = fmt.Sprintf("%s: место %d — %s", msg, i+1, err.Error())
+			}
+			return DeliveryResult{IsValid: false, ErrorMessage: msg}
+		}
+	}
+	return dc.calculate(service, fromCity, toCity, TotalVolume(pieces), TotalWeight(pieces))
+}
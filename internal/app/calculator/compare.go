@@ -0,0 +1,132 @@
+package calculator
+
+import (
+	"sort"
+	"sync"
+
+	"rip-go-app/internal/app/ds"
+)
+
+// DefaultCostWeight - вес стоимости в оценке "цена/качество" (остальное — вес сроков)
+const DefaultCostWeight = 0.5
+
+// ComparisonOption - вариант доставки одним видом транспорта
+type ComparisonOption struct {
+	TransportServiceID int     `json:"service_id"`
+	ServiceName        string  `json:"service_name"`
+	IsValid            bool    `json:"is_valid"`
+	RejectionReason    string  `json:"rejection_reason,omitempty"`
+	DeliveryDays       int     `json:"delivery_days,omitempty"`
+	TotalCost          float64 `json:"total_cost,omitempty"`
+	Distance           float64 `json:"distance,omitempty"`
+	Volume             float64 `json:"volume,omitempty"`
+	ChargeableWeight   float64 `json:"chargeable_weight,omitempty"`
+	Score              float64 `json:"score,omitempty"` // меньше — лучше
+	Cheapest           bool    `json:"cheapest"`
+	Fastest            bool    `json:"fastest"`
+	BestValue          bool    `json:"best_value"`
+}
+
+// ComparisonResult - сравнение всех видов транспорта для одного груза и маршрута
+type ComparisonResult struct {
+	Options     []ComparisonOption `json:"options"`
+	CheapestID  int                `json:"cheapest_service_id,omitempty"`
+	FastestID   int                `json:"fastest_service_id,omitempty"`
+	BestValueID int                `json:"best_value_service_id,omitempty"`
+	CostWeight  float64            `json:"cost_weight"`
+}
+
+// CompareServices - параллельный расчет доставки всеми услугами и выбор самого дешёвого,
+// самого быстрого и оптимального варианта. costWeight (0..1) задаёт вес стоимости
+// относительно сроков в оценке "цена/качество".
+func (dc *DeliveryCalculator) CompareServices(services []ds.TransportService, fromCity, toCity string, length, width, height, weight, costWeight float64) ComparisonResult {
+	if costWeight < 0 || costWeight > 1 {
+		costWeight = DefaultCostWeight
+	}
+
+	// Расстояние одинаково для всех вариантов — считаем его один раз
+	calc := &DeliveryCalculator{distances: newCachedDistances(dc.distances)}
+
+	options := make([]ComparisonOption, len(services))
+	var wg sync.WaitGroup
+	for i, svc := range services {
+		wg.Add(1)
+		go func(i int, svc ds.TransportService) {
+			defer wg.Done()
+			res := calc.CalculateDelivery(svc, fromCity, toCity, length, width, height, weight)
+			options[i] = ComparisonOption{
+				TransportServiceID: svc.ID,
+				ServiceName:        svc.Name,
+				IsValid:            res.IsValid,
+				RejectionReason:    res.ErrorMessage,
+				DeliveryDays:       res.DeliveryDays,
+				TotalCost:          res.TotalCost,
+				Distance:           res.Distance,
+				Volume:             res.Volume,
+				ChargeableWeight:   res.ChargeableWeight,
+			}
+		}(i, svc)
+	}
+	wg.Wait()
+
+	result := ComparisonResult{Options: options, CostWeight: costWeight}
+	markBestOptions(&result)
+	return result
+}
+
+// markBestOptions - отметка самого дешёвого, самого быстрого и оптимального варианта
+func markBestOptions(result *ComparisonResult) {
+	cheapest, fastest := -1, -1
+	for i, opt := range result.Options {
+		if !opt.IsValid {
+			continue
+		}
+		if cheapest < 0 || opt.TotalCost < result.Options[cheapest].TotalCost {
+			cheapest = i
+		}
+		if fastest < 0 || opt.DeliveryDays < result.Options[fastest].DeliveryDays {
+			fastest = i
+		}
+	}
+	if cheapest < 0 {
+		return
+	}
+
+	// Оценка: взвешенная сумма стоимости и сроков относительно лучших значений
+	minCost := result.Options[cheapest].TotalCost
+	minDays := float64(result.Options[fastest].DeliveryDays)
+	best := -1
+	for i := range result.Options {
+		opt := &result.Options[i]
+		if !opt.IsValid {
+			continue
+		}
+		costRatio, daysRatio := 1.0, 1.0
+		if minCost > 0 {
+			costRatio = opt.TotalCost / minCost
+		}
+		if minDays > 0 {
+			daysRatio = float64(opt.DeliveryDays) / minDays
+		}
+		opt.Score = result.CostWeight*costRatio + (1-result.CostWeight)*daysRatio
+		if best < 0 || opt.Score < result.Options[best].Score {
+			best = i
+		}
+	}
+
+	result.Options[cheapest].Cheapest = true
+	result.Options[fastest].Fastest = true
+	result.Options[best].BestValue = true
+	result.CheapestID = result.Options[cheapest].TransportServiceID
+	result.FastestID = result.Options[fastest].TransportServiceID
+	result.BestValueID = result.Options[best].TransportServiceID
+
+	// Сначала допустимые варианты по оценке, затем отклонённые
+	sort.SliceStable(result.Options, func(i, j int) bool {
+		a, b := result.Options[i], result.Options[j]
+		if a.IsValid != b.IsValid {
+			return a.IsValid
+		}
+		return a.Score < b.Score
+	})
+}
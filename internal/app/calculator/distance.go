@@ -0,0 +1,66 @@
+package calculator
+
+import (
+	"math"
+	"sync"
+)
+
+// DistanceProvider - источник расстояний между городами (справочник городов в БД)
+type DistanceProvider interface {
+	// Distance - расстояние по дорогам в км; ошибка, если город неизвестен
+	Distance(fromCity, toCity string) (float64, error)
+}
+
+// RoadFactor - во сколько раз путь по дорогам длиннее расстояния по прямой.
+// Используется, когда для пары городов нет явного расстояния в справочнике.
+const RoadFactor = 1.3
+
+// earthRadiusKm - средний радиус Земли
+const earthRadiusKm = 6371.0
+
+// GreatCircleDistance - расстояние по дуге большого круга между двумя точками, км
+func GreatCircleDistance(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+// EstimateRoadDistance - оценка расстояния по дорогам по координатам городов
+func EstimateRoadDistance(lat1, lon1, lat2, lon2 float64) float64 {
+	return math.Round(GreatCircleDistance(lat1, lon1, lat2, lon2) * RoadFactor)
+}
+
+// cachedDistances - запоминает расстояния на время одного расчета,
+// чтобы параллельные расчеты по одной паре городов не обращались к справочнику повторно
+type cachedDistances struct {
+	source DistanceProvider
+	mu     sync.Mutex
+	cache  map[[2]string]cachedDistance
+}
+
+type cachedDistance struct {
+	distance float64
+	err      error
+}
+
+func newCachedDistances(source DistanceProvider) *cachedDistances {
+	return &cachedDistances{source: source, cache: make(map[[2]string]cachedDistance)}
+}
+
+// Distance - расстояние из кеша или из исходного справочника
+func (c *cachedDistances) Distance(fromCity, toCity string) (float64, error) {
+	key := [2]string{fromCity, toCity}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if d, ok := c.cache[key]; ok {
+		return d.distance, d.err
+	}
+	distance, err := c.source.Distance(fromCity, toCity)
+	c.cache[key] = cachedDistance{distance: distance, err: err}
+	return distance, err
+}
@@ -0,0 +1,110 @@
+package calculator
+
+import (
+	"fmt"
+	"math"
+
+	"rip-go-app/internal/app/ds"
+)
+
+// Перевалка груза в промежуточном хабе между плечами маршрута
+const (
+	TransshipmentFee  = 5000.0 // руб за одну перевалку
+	TransshipmentDays = 1      // дней на одну перевалку
+)
+
+// RouteLeg - плечо мультимодального маршрута
+type RouteLeg struct {
+	Service  ds.TransportService
+	FromCity string
+	ToCity   string
+}
+
+// RouteLegResult - результат расчета одного плеча
+type RouteLegResult struct {
+	Sequence           int     `json:"sequence"`
+	TransportServiceID int     `json:"service_id"`
+	ServiceName        string  `json:"service_name"`
+	FromCity           string  `json:"from_city"`
+	ToCity             string  `json:"to_city"`
+	Distance           float64 `json:"distance"`
+	DeliveryDays       int     `json:"delivery_days"`
+	Cost               float64 `json:"cost"`
+}
+
+// RouteResult - результат расчета маршрута из нескольких плеч
+type RouteResult struct {
+	Legs              []RouteLegResult `json:"legs"`
+	Transshipments    int              `json:"transshipments"`
+	TransshipmentCost float64          `json:"transshipment_cost"`
+	TransshipmentDays int              `json:"transshipment_days"`
+	Distance          float64          `json:"distance"`
+	Volume            float64          `json:"volume"`
+	DeliveryDays      int              `json:"delivery_days"`
+	TotalCost         float64          `json:"total_cost"`
+	IsValid           bool             `json:"is_valid"`
+	ErrorMessage      string           `json:"error_message,omitempty"`
+}
+
+// CalculateRoute - расчет доставки по маршруту из нескольких плеч (например, фура → корабль → фура).
+// Груз проверяется по ограничениям транспорта каждого плеча, стоимость и сроки суммируются,
+// на каждом промежуточном хабе добавляются дни и стоимость перевалки.
+func (dc *DeliveryCalculator) CalculateRoute(legs []RouteLeg, pieces []CargoPiece) RouteResult {
+	result := RouteResult{
+		IsValid: true,
+		Volume:  TotalVolume(pieces),
+	}
+
+	if len(legs) == 0 {
+		result.IsValid = false
+		result.ErrorMessage = "Маршрут не содержит ни одного плеча"
+		return result
+	}
+
+	for i, leg := range legs {
+		// Плечи должны стыковаться: конец предыдущего плеча — начало следующего
+		if i > 0 {
+			gap, err := dc.distances.Distance(legs[i-1].ToCity, leg.FromCity)
+			if err != nil {
+				result.IsValid = false
+				result.ErrorMessage = err.Error()
+				return result
+			}
+			if gap != 0 {
+				result.IsValid = false
+				result.ErrorMessage = fmt.Sprintf("Плечо %d начинается не в городе окончания плеча %d", i+1, i)
+				return result
+			}
+		}
+
+		res := dc.CalculateCargoDelivery(leg.Service, leg.FromCity, leg.ToCity, pieces)
+		if !res.IsValid {
+			result.IsValid = false
+			result.ErrorMessage = fmt.Sprintf("Плечо %d (%s): %s", i+1, leg.Service.Name, res.ErrorMessage)
+			return result
+		}
+
+		result.Legs = append(result.Legs, RouteLegResult{
+			Sequence:           i + 1,
+			TransportServiceID: leg.Service.ID,
+			ServiceName:        leg.Service.Name,
+			FromCity:           leg.FromCity,
+			ToCity:             leg.ToCity,
+			Distance:           res.Distance,
+			DeliveryDays:       res.DeliveryDays,
+			Cost:               res.TotalCost,
+		})
+		result.Distance += res.Distance
+		result.DeliveryDays += res.DeliveryDays
+		result.TotalCost += res.TotalCost
+	}
+
+	// Перевалка на каждом промежуточном хабе
+	result.Transshipments = len(legs) - 1
+	result.TransshipmentCost = float64(result.Transshipments) * TransshipmentFee
+	result.TransshipmentDays = result.Transshipments * TransshipmentDays
+	result.DeliveryDays += result.TransshipmentDays
+	result.TotalCost = math.Round((result.TotalCost+result.TransshipmentCost)*100) / 100
+
+	return result
+}
@@ -0,0 +1,32 @@
+package calculator
+
+import "math"
+
+// RemainingTransit - оценка оставшегося пути груза
+type RemainingTransit struct {
+	Distance float64 `json:"distance"` // км до города назначения
+	Days     int     `json:"days"`     // дней в пути
+}
+
+// EstimateRemaining - остаток пути от текущего города груза до конца маршрута.
+// legs — ещё не пройденные плечи: первое из них груз проходит от currentCity,
+// остальные целиком, с перевалкой перед каждым. Дни считаются по скорости (км в день) транспорта плеча.
+func (dc *DeliveryCalculator) EstimateRemaining(legs []RouteLeg, currentCity string) (RemainingTransit, error) {
+	var result RemainingTransit
+	for i, leg := range legs {
+		from := leg.FromCity
+		if i == 0 {
+			from = currentCity
+		} else {
+			result.Days += TransshipmentDays
+		}
+
+		distance, err := dc.distances.Distance(from, leg.ToCity)
+		if err != nil {
+			return RemainingTransit{}, err
+		}
+		result.Distance += distance
+		result.Days += int(math.Ceil(distance / dc.getDeliveryCoefficients(leg.Service).DistancePerDay))
+	}
+	return result, nil
+}
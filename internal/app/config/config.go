@@ -18,7 +18,9 @@ type Config struct {
 	KeyFile     string
 	
 	// JWT Configuration
-	JWTSecret             string
+	// Каталог ключей подписи: <kid>.pem (RSA или Ed25519), <kid>.pub.pem - только проверка.
+	// Ключ с датой в начале kid (2026-01-01-...) подписывает токены с этой даты.
+	JWTKeysDir            string
 	JWTAccessTokenExpire  int
 	JWTRefreshTokenExpire int
 	
@@ -27,6 +29,16 @@ type Config struct {
 	RedisPort     int
 	RedisPassword string
 	RedisDB       int
+
+	// Хранилище отзыва токенов: postgres (по умолчанию), redis или memory (один экземпляр, тесты)
+	RevocationStore string
+	
+	// Гостевые сессии
+	GuestSessionTTL int // часов без активности до удаления гостевого черновика
+
+	// Фоновые задачи
+	AutoRejectAfterDays     int // дней без рассмотрения до автоматического отклонения сформированной заявки
+	SoftDeleteRetentionDays int // дней хранения удалённых записей до окончательного удаления
 }
 
 func NewConfig() (*Config, error) {
@@ -56,6 +68,19 @@ func NewConfig() (*Config, error) {
 		return nil, err
 	}
 
+	if cfg.RevocationStore == "" {
+		cfg.RevocationStore = "postgres"
+	}
+	if cfg.GuestSessionTTL <= 0 {
+		cfg.GuestSessionTTL = 72
+	}
+	if cfg.AutoRejectAfterDays <= 0 {
+		cfg.AutoRejectAfterDays = 14
+	}
+	if cfg.SoftDeleteRetentionDays <= 0 {
+		cfg.SoftDeleteRetentionDays = 30
+	}
+
 	log.Info("config parsed")
 
 	return cfg, nil
@@ -0,0 +1,27 @@
+package ds
+
+// CostBreakdown - из чего сложилась стоимость доставки
+type CostBreakdown struct {
+	BasePrice            float64 `json:"base_price" gorm:"not null;default:0"`            // базовая цена услуги
+	ActualWeight         float64 `json:"actual_weight" gorm:"not null;default:0"`         // фактический вес, кг
+	VolumetricWeight     float64 `json:"volumetric_weight" gorm:"not null;default:0"`     // объемный вес, кг
+	ChargeableWeight     float64 `json:"chargeable_weight" gorm:"not null;default:0"`     // оплачиваемый вес = max(фактический, объемный)
+	DistanceCost         float64 `json:"distance_cost" gorm:"not null;default:0"`         // расстояние × ставка за км
+	WeightCost           float64 `json:"weight_cost" gorm:"not null;default:0"`           // оплачиваемый вес × ставка за кг
+	VolumeCost           float64 `json:"volume_cost" gorm:"not null;default:0"`           // объем × ставка за м³
+	Subtotal             float64 `json:"subtotal" gorm:"not null;default:0"`              // сумма до коэффициента сложности
+	ComplexityMultiplier float64 `json:"complexity_multiplier" gorm:"not null;default:0"` // итоговый коэффициент сложности
+	ComplexityCapApplied bool    `json:"complexity_cap_applied" gorm:"not null;default:false"`
+	MinCostApplied       bool    `json:"min_cost_applied" gorm:"not null;default:false"` // стоимость поднята до базовой цены
+	Total                float64 `json:"total" gorm:"not null;default:0"`
+}
+
+// DaysBreakdown - из чего сложился срок доставки
+type DaysBreakdown struct {
+	BaseDays       int  `json:"base_days" gorm:"not null;default:0"`       // базовый срок услуги
+	DistanceDays   int  `json:"distance_days" gorm:"not null;default:0"`   // дни в пути по расстоянию
+	ComplexityDays int  `json:"complexity_days" gorm:"not null;default:0"` // доп. дни за объем и вес
+	MinDays        int  `json:"min_days" gorm:"not null;default:0"`        // минимальный срок по тарифу
+	MinDaysApplied bool `json:"min_days_applied" gorm:"not null;default:false"`
+	Total          int  `json:"total" gorm:"not null;default:0"`
+}
@@ -0,0 +1,19 @@
+package ds
+
+// CargoItem - грузовое место (позиция груза) в логистической заявке
+type CargoItem struct {
+	ID                int     `json:"id" gorm:"primaryKey"`
+	LogisticRequestID int     `json:"logistic_request_id" gorm:"not null;index"`
+	Quantity          int     `json:"quantity" gorm:"not null;default:1"`
+	Length            float64 `json:"length" gorm:"not null"`    // м, одного места
+	Width             float64 `json:"width" gorm:"not null"`     // м, одного места
+	Height            float64 `json:"height" gorm:"not null"`    // м, одного места
+	Weight            float64 `json:"weight" gorm:"not null"`    // кг, одного места
+	Stackable         bool    `json:"stackable" gorm:"not null"` // по умолчанию true, задаётся явно в toCargoItem
+	Fragile           bool    `json:"fragile" gorm:"not null;default:false"`
+	Description       string  `json:"description" gorm:"type:text"`
+}
+
+func (CargoItem) TableName() string {
+	return "cargo_items"
+}
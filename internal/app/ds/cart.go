@@ -1,5 +1,7 @@
 package ds
 
+import "gorm.io/gorm"
+
 // DraftLogisticRequest — представление черновика логистической заявки (раньше "корзина")
 type DraftLogisticRequest struct {
     ID        int          `json:"id" gorm:"primaryKey"`    // это id заявки в таблице logistic_requests
@@ -16,6 +18,7 @@ type DraftLogisticRequestService struct {
     LogisticRequestID  int `json:"logistic_request_id" gorm:"not null"`
     TransportServiceID int `json:"transport_service_id" gorm:"not null"`
     Quantity           int `json:"quantity" gorm:"not null"`
+    DeletedAt          gorm.DeletedAt `json:"-"`
 }
 
 func (DraftLogisticRequestService) TableName() string { return "logistic_request_services" }
@@ -0,0 +1,49 @@
+package ds
+
+import "time"
+
+// City - город из справочника
+type City struct {
+	ID        int         `json:"id" gorm:"primaryKey"`
+	Name      string      `json:"name" gorm:"uniqueIndex;not null"`
+	Region    string      `json:"region"`
+	Latitude  float64     `json:"latitude" gorm:"not null"`
+	Longitude float64     `json:"longitude" gorm:"not null"`
+	Aliases   []CityAlias `json:"aliases,omitempty" gorm:"foreignKey:CityID"`
+
+	// Системные поля
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (City) TableName() string {
+	return "cities"
+}
+
+// CityAlias - альтернативное написание названия города ("спб", "питер")
+type CityAlias struct {
+	ID     int    `json:"id" gorm:"primaryKey"`
+	CityID int    `json:"city_id" gorm:"not null;index"`
+	Alias  string `json:"alias" gorm:"uniqueIndex;not null"` // хранится в нормализованном виде
+}
+
+func (CityAlias) TableName() string {
+	return "city_aliases"
+}
+
+// RouteDistance - известное расстояние по дорогам между двумя городами.
+// Хранится одна запись на пару, поиск выполняется в обе стороны.
+type RouteDistance struct {
+	ID         int     `json:"id" gorm:"primaryKey"`
+	FromCityID int     `json:"from_city_id" gorm:"not null;uniqueIndex:idx_route_distance_pair"`
+	ToCityID   int     `json:"to_city_id" gorm:"not null;uniqueIndex:idx_route_distance_pair"`
+	DistanceKm float64 `json:"distance_km" gorm:"not null"`
+
+	// Связи
+	FromCity City `json:"-" gorm:"foreignKey:FromCityID"`
+	ToCity   City `json:"-" gorm:"foreignKey:ToCityID"`
+}
+
+func (RouteDistance) TableName() string {
+	return "route_distances"
+}
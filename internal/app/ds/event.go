@@ -0,0 +1,40 @@
+package ds
+
+import "time"
+
+// LogisticRequestEvent - запись журнала изменений заявки: смена статуса, изменение услуг, мест, маршрута или полей
+type LogisticRequestEvent struct {
+	ID                int    `json:"id" gorm:"primaryKey"`
+	LogisticRequestID int    `json:"logistic_request_id" gorm:"not null;index"`
+	ActorID           *int   `json:"actor_id"` // nil — изменение без пользователя (гость, система)
+	Type              string `json:"type" gorm:"type:varchar(32);not null"`
+	// ID связанной сущности: услуги для service_*, места для cargo_item_*
+	SubjectID *int   `json:"subject_id,omitempty"`
+	Field     string `json:"field,omitempty" gorm:"type:varchar(64)"`
+	OldValue  string `json:"old_value,omitempty" gorm:"type:text"`
+	NewValue  string `json:"new_value,omitempty" gorm:"type:text"`
+	Comment   string `json:"comment,omitempty" gorm:"type:text"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	// Связи
+	Actor *User `json:"actor,omitempty" gorm:"foreignKey:ActorID"`
+}
+
+func (LogisticRequestEvent) TableName() string {
+	return "logistic_request_events"
+}
+
+// Типы событий журнала заявки
+const (
+	EventStatusChanged    = "status_changed"
+	EventFieldChanged     = "field_changed"
+	EventServiceAdded     = "service_added"
+	EventServiceUpdated   = "service_updated"
+	EventServiceRemoved   = "service_removed"
+	EventCargoItemAdded   = "cargo_item_added"
+	EventCargoItemUpdated = "cargo_item_updated"
+	EventCargoItemRemoved = "cargo_item_removed"
+	EventRouteChanged     = "route_changed"
+	EventCheckpointAdded  = "checkpoint_added"
+)
@@ -0,0 +1,14 @@
+package ds
+
+import "time"
+
+// FilterPreset - сохранённый набор фильтров списка заявок (у каждого пользователя свои).
+// Filters - параметры запроса GET /api/logistic-requests без параметров страницы.
+type FilterPreset struct {
+	ID        int               `json:"id" gorm:"primaryKey"`
+	UserID    int               `json:"user_id" gorm:"not null;uniqueIndex:idx_filter_presets_user_name"`
+	Name      string            `json:"name" gorm:"type:varchar(100);not null;uniqueIndex:idx_filter_presets_user_name"`
+	Filters   map[string]string `json:"filters" gorm:"type:jsonb;serializer:json;not null"`
+	CreatedAt time.Time         `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time         `json:"updated_at" gorm:"autoUpdateTime"`
+}
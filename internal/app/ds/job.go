@@ -0,0 +1,36 @@
+package ds
+
+import "time"
+
+// JobLease - аренда фоновой задачи: задачу выполняет только реплика-владелец до истечения аренды
+type JobLease struct {
+	Name      string    `json:"name" gorm:"primaryKey;type:varchar(64)"`
+	Holder    string    `json:"holder" gorm:"type:varchar(128);not null"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null"`
+}
+
+func (JobLease) TableName() string {
+	return "job_leases"
+}
+
+// JobRun - запись истории запуска фоновой задачи
+type JobRun struct {
+	ID         int        `json:"id" gorm:"primaryKey"`
+	Name       string     `json:"name" gorm:"type:varchar(64);not null;index"`
+	Holder     string     `json:"holder" gorm:"type:varchar(128);not null"`
+	Status     string     `json:"status" gorm:"type:varchar(16);not null"`
+	Result     string     `json:"result,omitempty" gorm:"type:text"`
+	Error      string     `json:"error,omitempty" gorm:"type:text"`
+	StartedAt  time.Time  `json:"started_at" gorm:"not null;index"`
+	FinishedAt *time.Time `json:"finished_at"`
+}
+
+func (JobRun) TableName() string {
+	return "job_runs"
+}
+
+// Статусы запуска фоновой задачи
+const (
+	JobRunSucceeded = "succeeded"
+	JobRunFailed    = "failed"
+)
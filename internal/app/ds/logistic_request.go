@@ -1,6 +1,10 @@
 package ds
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 // LogisticRequest - модель логистической заявки
 type LogisticRequest struct {
@@ -9,15 +13,27 @@ type LogisticRequest struct {
     IsDraft   bool           `json:"is_draft" gorm:"not null;default:true"`
     FromCity  string         `json:"from_city"`
     ToCity    string         `json:"to_city"`
+    // Города из справочника (заполняются при формировании заявки)
+    FromCityID *int          `json:"from_city_id"`
+    ToCityID   *int          `json:"to_city_id"`
     // Параметры груза
     Weight    float64        `json:"weight" gorm:"not null;default:0"`
     Length    float64        `json:"length" gorm:"not null;default:0"`
     Width     float64        `json:"width" gorm:"not null;default:0"`
     Height    float64        `json:"height" gorm:"not null;default:0"`
+    Volume    float64        `json:"volume" gorm:"not null;default:0"` // общий объем всех мест, м³
+    // Грузовые места (вес и объем заявки — их сумма, габариты — наибольшие среди мест)
+    CargoItems []CargoItem   `json:"cargo_items,omitempty" gorm:"foreignKey:LogisticRequestID"`
 	Services  []LogisticRequestService `json:"services" gorm:"foreignKey:LogisticRequestID"`
+    // Плечи маршрута (для мультимодальной доставки)
+    Legs      []LogisticRequestLeg `json:"legs,omitempty" gorm:"foreignKey:LogisticRequestID"`
     TotalCost float64        `json:"total_cost"`
     TotalDays int            `json:"total_days"`
+    // Зафиксированный при формировании расчет
+    QuoteID   *int           `json:"quote_id"`
     Status    string         `json:"status" gorm:"type:varchar(32);not null;default:'draft'"`
+    // Публичный номер отслеживания (выдаётся при формировании)
+    TrackingNumber *string   `json:"tracking_number" gorm:"type:varchar(32);uniqueIndex"`
     
     // Системные поля
     CreatorID   int        `json:"creator_id" gorm:"not null"`
@@ -25,8 +41,11 @@ type LogisticRequest struct {
     CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime"`
     FormedAt    *time.Time `json:"formed_at"`
     CompletedAt *time.Time `json:"completed_at"`
+    ShippedAt   *time.Time `json:"shipped_at"`
+    DeliveredAt *time.Time `json:"delivered_at"`
+    CancelledAt *time.Time `json:"cancelled_at"`
     UpdatedAt   time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
-    DeletedAt   *time.Time `json:"-" gorm:"index"`
+    DeletedAt   gorm.DeletedAt `json:"deleted_at" gorm:"index"` // мягкое удаление
     
     // Связи
     Creator   User `json:"creator" gorm:"foreignKey:CreatorID"`
@@ -37,12 +56,15 @@ func (LogisticRequest) TableName() string {
 	return "logistic_requests"
 }
 
-// LogisticRequest statuses
+// LogisticRequest statuses (допустимые переходы — в пакете workflow)
 const (
     StatusDraft     = "draft"     // черновик
     StatusFormed    = "formed"    // сформирован
-    StatusCompleted = "completed" // завершён
+    StatusCompleted = "completed" // завершён (одобрен модератором, цена зафиксирована)
     StatusRejected  = "rejected"  // отклонён
+    StatusShipped   = "shipped"   // груз отправлен
+    StatusDelivered = "delivered" // груз доставлен
+    StatusCancelled = "cancelled" // отменён
     StatusDeleted   = "deleted"   // удалён
 )
 
@@ -55,6 +77,14 @@ type LogisticRequestService struct {
 	Comment            string            `json:"comment" gorm:"type:text"`
 	SortOrder          int               `json:"sort_order" gorm:"column:order;not null;default:0"`
 	
+	// Расчет по строке, фиксируется при завершении заявки
+	Cost          float64       `json:"cost" gorm:"not null;default:0"`
+	DeliveryDays  int           `json:"delivery_days" gorm:"not null;default:0"`
+	CostBreakdown CostBreakdown `json:"cost_breakdown" gorm:"embedded;embeddedPrefix:cost_"`
+	DaysBreakdown DaysBreakdown `json:"days_breakdown" gorm:"embedded;embeddedPrefix:days_"`
+	// Мягкое удаление строки (при повторном добавлении услуги строка восстанавливается)
+	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
+	
 	// Связи
 	TransportService TransportService `json:"service" gorm:"foreignKey:TransportServiceID"`
 }
@@ -62,3 +92,25 @@ type LogisticRequestService struct {
 func (LogisticRequestService) TableName() string {
 	return "logistic_request_services"
 }
+
+// LogisticRequestLeg - плечо мультимодального маршрута заявки
+type LogisticRequestLeg struct {
+	ID                 int     `json:"id" gorm:"primaryKey"`
+	LogisticRequestID  int     `json:"logistic_request_id" gorm:"not null;index"`
+	Sequence           int     `json:"sequence" gorm:"not null"`
+	TransportServiceID int     `json:"transport_service_id" gorm:"not null"`
+	FromCity           string  `json:"from_city" gorm:"not null"`
+	ToCity             string  `json:"to_city" gorm:"not null"`
+	FromCityID         *int    `json:"from_city_id"`
+	ToCityID           *int    `json:"to_city_id"`
+	Distance           float64 `json:"distance"`
+	DeliveryDays       int     `json:"delivery_days"`
+	Cost               float64 `json:"cost"`
+
+	// Связи
+	TransportService TransportService `json:"service" gorm:"foreignKey:TransportServiceID"`
+}
+
+func (LogisticRequestLeg) TableName() string {
+	return "logistic_request_legs"
+}
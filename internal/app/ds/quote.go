@@ -0,0 +1,55 @@
+package ds
+
+import "time"
+
+// Quote - зафиксированный расчет стоимости заявки (снимок входных данных, тарифов и результата).
+// Пока расчет действителен, заявка завершается по зафиксированной цене.
+type Quote struct {
+	ID                int `json:"id" gorm:"primaryKey"`
+	LogisticRequestID int `json:"logistic_request_id" gorm:"not null;index"`
+
+	// Входные данные калькулятора
+	FromCity string  `json:"from_city"`
+	ToCity   string  `json:"to_city"`
+	Weight   float64 `json:"weight"`
+	Volume   float64 `json:"volume"`
+	Length   float64 `json:"length"`
+	Width    float64 `json:"width"`
+	Height   float64 `json:"height"`
+
+	// Результат
+	TotalCost float64     `json:"total_cost"`
+	TotalDays int         `json:"total_days"`
+	Lines     []QuoteLine `json:"lines" gorm:"foreignKey:QuoteID"`
+
+	// Срок действия
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+	ValidUntil time.Time `json:"valid_until" gorm:"not null;index"`
+	// Момент, когда фоновая задача отметила расчет истёкшим
+	ExpiredAt *time.Time `json:"expired_at,omitempty"`
+}
+
+func (Quote) TableName() string {
+	return "quotes"
+}
+
+// IsExpired - истёк ли срок действия расчета
+func (q Quote) IsExpired(now time.Time) bool {
+	return q.ExpiredAt != nil || now.After(q.ValidUntil)
+}
+
+// QuoteLine - расчет по одной услуге в зафиксированном расчете
+type QuoteLine struct {
+	ID                 int           `json:"id" gorm:"primaryKey"`
+	QuoteID            int           `json:"quote_id" gorm:"not null;index"`
+	TransportServiceID int           `json:"transport_service_id" gorm:"not null"`
+	TariffVersion      int           `json:"tariff_version" gorm:"not null"`
+	Cost               float64       `json:"cost"`
+	DeliveryDays       int           `json:"delivery_days"`
+	CostBreakdown      CostBreakdown `json:"cost_breakdown" gorm:"embedded;embeddedPrefix:cost_"`
+	DaysBreakdown      DaysBreakdown `json:"days_breakdown" gorm:"embedded;embeddedPrefix:days_"`
+}
+
+func (QuoteLine) TableName() string {
+	return "quote_lines"
+}
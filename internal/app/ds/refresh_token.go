@@ -0,0 +1,42 @@
+package ds
+
+import "time"
+
+// RefreshToken - выданный refresh токен (по jti). Токены одного входа образуют семейство (сессию устройства):
+// при обновлении токен помечается использованным и заменяется следующим в том же семействе.
+type RefreshToken struct {
+	ID         string     `json:"id" gorm:"primaryKey;type:varchar(36)"` // jti
+	FamilyID   string     `json:"family_id" gorm:"type:varchar(36);not null;index"`
+	UserID     int        `json:"user_id" gorm:"not null;index"`
+	UserAgent  string     `json:"user_agent" gorm:"type:text"`
+	IP         string     `json:"ip" gorm:"type:varchar(64)"`
+	IssuedAt   time.Time  `json:"issued_at" gorm:"not null"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"not null;index"`
+	UsedAt     *time.Time `json:"used_at"`     // обменян на следующий токен
+	ReplacedBy string     `json:"replaced_by"` // jti следующего токена
+	RevokedAt  *time.Time `json:"revoked_at"`  // отозван вместе с семейством
+}
+
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+// RevokedToken - отозванный до истечения срока токен (хранилище отзыва в Postgres)
+type RevokedToken struct {
+	JTI       string    `json:"jti" gorm:"primaryKey;type:varchar(36)"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`
+}
+
+func (RevokedToken) TableName() string {
+	return "revoked_tokens"
+}
+
+// RevokedSession - завершённая сессия: access токены с этим sid недействительны до истечения их срока
+type RevokedSession struct {
+	SessionID string    `json:"session_id" gorm:"primaryKey;type:varchar(36)"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`
+}
+
+func (RevokedSession) TableName() string {
+	return "revoked_sessions"
+}
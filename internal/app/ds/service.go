@@ -1,6 +1,10 @@
 package ds
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 // TransportService - модель услуги (вид грузоперевозки)
 type TransportService struct {
@@ -12,13 +16,44 @@ type TransportService struct {
 	DeliveryDays int     `json:"delivery_days" gorm:"not null"`
 	MaxWeight    float64 `json:"max_weight" gorm:"not null"`
 	MaxVolume    float64 `json:"max_volume" gorm:"not null"`
-	
+
+	// Тарифные параметры калькулятора
+	Tariff `gorm:"embedded"`
+	// Версия тарифа, увеличивается при каждом изменении услуги
+	TariffVersion int `json:"tariff_version" gorm:"not null;default:1"`
+
 	// Системные поля
-	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
-	DeletedAt *time.Time `json:"-" gorm:"index"`
+	CreatedAt time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"` // мягкое удаление
 }
 
 func (TransportService) TableName() string {
 	return "transport_services"
 }
+
+// Tariff - тарифные параметры услуги, по которым считает калькулятор.
+// Нулевое значение поля означает "использовать значение по умолчанию".
+type Tariff struct {
+	// Стоимость
+	DistanceRate float64 `json:"distance_rate" gorm:"not null;default:0"` // руб/км
+	WeightRate   float64 `json:"weight_rate" gorm:"not null;default:0"`   // руб/кг
+	VolumeRate   float64 `json:"volume_rate" gorm:"not null;default:0"`   // руб/м³
+
+	// Сроки
+	DistancePerDay  float64 `json:"distance_per_day" gorm:"not null;default:0"`  // км в день
+	MinDeliveryDays int     `json:"min_delivery_days" gorm:"not null;default:0"` // минимальный срок, дней
+
+	// Максимальные габариты груза, м
+	MaxLength float64 `json:"max_length" gorm:"not null;default:0"`
+	MaxWidth  float64 `json:"max_width" gorm:"not null;default:0"`
+	MaxHeight float64 `json:"max_height" gorm:"not null;default:0"`
+
+	// Сложность груза
+	ComplexityFactor      float64 `json:"complexity_factor" gorm:"not null;default:0"`        // доп. множитель стоимости (авиа — 1.2)
+	ComplexityCap         float64 `json:"complexity_cap" gorm:"not null;default:0"`           // максимальный коэффициент сложности
+	ComplexityDaysDivisor int     `json:"complexity_days_divisor" gorm:"not null;default:0"` // делитель доп. дней за сложность (авиа — 2)
+
+	// Объемный вес: делитель в см³/кг (авиа — 6000, т.е. 167 кг/м³; авто — 3000–4000)
+	VolumetricDivisor float64 `json:"volumetric_divisor" gorm:"not null;default:0"`
+}
@@ -0,0 +1,50 @@
+package ds
+
+import "time"
+
+// ServiceTariff - версия тарифа услуги с периодом действия [ValidFrom, ValidTo).
+// Текущая версия дублируется в полях TransportService; история нужна для пересчёта старых заявок
+// и для запланированных изменений цен.
+type ServiceTariff struct {
+	ID                 int     `json:"id" gorm:"primaryKey"`
+	TransportServiceID int     `json:"transport_service_id" gorm:"not null;uniqueIndex:idx_service_tariff_version"`
+	Version            int     `json:"version" gorm:"not null;uniqueIndex:idx_service_tariff_version"`
+	Price              float64 `json:"price" gorm:"not null"`
+	DeliveryDays       int     `json:"delivery_days" gorm:"not null"`
+	Tariff             `gorm:"embedded"`
+
+	// Период действия (ValidTo = nil — действует бессрочно)
+	ValidFrom time.Time  `json:"valid_from" gorm:"not null;index"`
+	ValidTo   *time.Time `json:"valid_to"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (ServiceTariff) TableName() string {
+	return "service_tariffs"
+}
+
+// ActiveAt - действует ли версия в указанный момент
+func (t ServiceTariff) ActiveAt(at time.Time) bool {
+	return !at.Before(t.ValidFrom) && (t.ValidTo == nil || at.Before(*t.ValidTo))
+}
+
+// ApplyTo - услуга с ценами этой версии тарифа
+func (t ServiceTariff) ApplyTo(s TransportService) TransportService {
+	s.Price = t.Price
+	s.DeliveryDays = t.DeliveryDays
+	s.Tariff = t.Tariff
+	s.TariffVersion = t.Version
+	return s
+}
+
+// SnapshotTariff - снимок текущего тарифа услуги
+func SnapshotTariff(s TransportService) ServiceTariff {
+	return ServiceTariff{
+		TransportServiceID: s.ID,
+		Version:            s.TariffVersion,
+		Price:              s.Price,
+		DeliveryDays:       s.DeliveryDays,
+		Tariff:             s.Tariff,
+	}
+}
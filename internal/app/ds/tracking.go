@@ -0,0 +1,38 @@
+package ds
+
+import "time"
+
+// TrackingCheckpoint - отметка о движении груза по заявке (вносит менеджер после завершения заявки)
+type TrackingCheckpoint struct {
+	ID                int       `json:"id" gorm:"primaryKey"`
+	LogisticRequestID int       `json:"logistic_request_id" gorm:"not null;index"`
+	EventType         string    `json:"event_type" gorm:"type:varchar(32);not null"`
+	City              string    `json:"city" gorm:"not null"`
+	CityID            *int      `json:"city_id"`
+	OccurredAt        time.Time `json:"occurred_at" gorm:"not null;index"`
+	Note              string    `json:"note,omitempty" gorm:"type:text"`
+
+	// Системные поля
+	CreatedByID *int      `json:"-"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (TrackingCheckpoint) TableName() string {
+	return "tracking_checkpoints"
+}
+
+// Типы отметок отслеживания
+const (
+	CheckpointPickedUp   = "picked_up"   // груз забран у отправителя
+	CheckpointInTransit  = "in_transit"  // в пути
+	CheckpointArrivedHub = "arrived_hub" // прибыл в промежуточный хаб
+	CheckpointDelivered  = "delivered"   // доставлен получателю
+)
+
+// CheckpointTypes - допустимые типы отметок
+var CheckpointTypes = map[string]bool{
+	CheckpointPickedUp:   true,
+	CheckpointInTransit:  true,
+	CheckpointArrivedHub: true,
+	CheckpointDelivered:  true,
+}
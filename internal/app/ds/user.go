@@ -16,6 +16,13 @@ type User struct {
 	Role      string    `json:"role" gorm:"not null;default:'buyer'"` // buyer, manager, admin
 	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	// Поколение токенов: токены с меньшим поколением отозваны (хранилище отзыва в Postgres)
+	TokenGeneration int `json:"-" gorm:"not null;default:0"`
+	// Блокировка администратором: вход и обновление токенов запрещены
+	DisabledAt *time.Time `json:"disabled_at,omitempty"`
+	// Пароль сброшен администратором: вход только после установки нового пароля по токену сброса
+	PasswordResetRequired bool `json:"password_reset_required" gorm:"not null;default:false"`
 }
 
 // UserRole - роли пользователей
@@ -24,3 +31,35 @@ const (
 	RoleManager = "manager"
 	RoleAdmin   = "admin"
 )
+
+// Roles - все роли пользователей
+var Roles = map[string]bool{
+	RoleBuyer:   true,
+	RoleManager: true,
+	RoleAdmin:   true,
+}
+
+// UserToken - одноразовый токен: приглашение менеджера или сброс пароля.
+// Хранится только хеш токена, сам токен выдается один раз при создании.
+type UserToken struct {
+	ID          int        `json:"id" gorm:"primaryKey"`
+	Purpose     string     `json:"purpose" gorm:"type:varchar(32);not null"`
+	TokenHash   string     `json:"-" gorm:"type:varchar(64);not null;uniqueIndex"`
+	UserID      *int       `json:"user_id"`         // сброс пароля: чей пароль
+	Email       string     `json:"email,omitempty"` // приглашение: для кого
+	Role        string     `json:"role,omitempty"`  // приглашение: роль нового пользователя
+	CreatedByID int        `json:"created_by_id" gorm:"not null"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	ExpiresAt   time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt      *time.Time `json:"used_at"`
+}
+
+func (UserToken) TableName() string {
+	return "user_tokens"
+}
+
+// Назначение одноразового токена
+const (
+	UserTokenInvite        = "invite"
+	UserTokenPasswordReset = "password_reset"
+)
@@ -1,13 +1,17 @@
 package handler
 
 import (
+    "encoding/json"
+    "errors"
     "github.com/gin-gonic/gin"
     "github.com/sirupsen/logrus"
     "rip-go-app/internal/app/ds"
     "rip-go-app/internal/app/repository"
+    "rip-go-app/internal/app/auth"
     "rip-go-app/internal/app/calculator"
     "rip-go-app/internal/app/service"
     "rip-go-app/internal/app/middleware"
+    "rip-go-app/internal/app/workflow"
     "net/http"
     "strconv"
     "strings"
@@ -37,11 +41,129 @@ func fail(ctx *gin.Context, code int, message string) {
     })
 }
 
+// currentActor - текущий пользователь как исполнитель перехода статуса заявки
+func (h *Handler) currentActor(ctx *gin.Context) (workflow.Actor, bool) {
+    userUUID, exists := middleware.GetUserUUID(ctx)
+    if !exists {
+        fail(ctx, http.StatusUnauthorized, "authentication required")
+        return workflow.Actor{}, false
+    }
+    user, err := h.Repository.GetUserByUUID(userUUID)
+    if err != nil {
+        fail(ctx, http.StatusInternalServerError, "failed to get user")
+        return workflow.Actor{}, false
+    }
+    return workflow.Actor{UserID: user.ID, Role: user.Role}, true
+}
+
+// authorizeLogisticRequest - заявка, доступная текущему пользователю: своя или чужая при праве other
+// (requests:read:any — просмотр, requests:write:any — изменение). Чужая заявка без права — 404.
+func (h *Handler) authorizeLogisticRequest(ctx *gin.Context, id int, other auth.Permission) (ds.LogisticRequest, workflow.Actor, bool) {
+    actor, ok := h.currentActor(ctx)
+    if !ok {
+        return ds.LogisticRequest{}, workflow.Actor{}, false
+    }
+    order, err := h.Repository.GetLogisticRequest(id)
+    if err != nil || (order.CreatorID != actor.UserID && !auth.HasPermission(actor.Role, other)) {
+        fail(ctx, http.StatusNotFound, "logistic request not found")
+        return ds.LogisticRequest{}, workflow.Actor{}, false
+    }
+    return order, actor, true
+}
+
+// actorID - ID текущего пользователя для журнала изменений (0 — без авторизации)
+func (h *Handler) actorID(ctx *gin.Context) int {
+    userUUID, exists := middleware.GetUserUUID(ctx)
+    if !exists {
+        return 0
+    }
+    user, err := h.Repository.GetUserByUUID(userUUID)
+    if err != nil {
+        return 0
+    }
+    return user.ID
+}
+
+// failTransition - ответ на ошибку смены статуса заявки
+func failTransition(ctx *gin.Context, err error) {
+    switch {
+    case errors.Is(err, workflow.ErrForbidden):
+        fail(ctx, http.StatusForbidden, err.Error())
+    case errors.Is(err, workflow.ErrInvalidTransition):
+        fail(ctx, http.StatusConflict, err.Error())
+    default:
+        fail(ctx, http.StatusBadRequest, err.Error())
+    }
+}
+
+// includeDeleted - параметр include_deleted списков; удалённые записи видит только администратор
+func includeDeleted(ctx *gin.Context) (bool, bool) {
+    if ctx.Query("include_deleted") != "true" {
+        return false, true
+    }
+    if role, _ := middleware.GetUserRole(ctx); !auth.HasPermission(role, auth.PermDeletedManage) {
+        fail(ctx, http.StatusForbidden, "include_deleted requires "+string(auth.PermDeletedManage))
+        return false, false
+    }
+    return true, true
+}
+
+// pageRequest - параметры страницы списка: limit, offset, cursor, sort
+func pageRequest(ctx *gin.Context) (repository.PageRequest, bool) {
+    page := repository.PageRequest{Cursor: ctx.Query("cursor"), Sort: ctx.Query("sort")}
+    for name, dst := range map[string]*int{"limit": &page.Limit, "offset": &page.Offset} {
+        if value := ctx.Query(name); value != "" {
+            n, err := strconv.Atoi(value)
+            if err != nil {
+                fail(ctx, http.StatusBadRequest, "invalid "+name)
+                return repository.PageRequest{}, false
+            }
+            *dst = n
+        }
+    }
+    return page, true
+}
+
+// failList - ответ на ошибку получения списка: неверные параметры страницы — 400
+func failList(ctx *gin.Context, err error, message string) {
+    if errors.Is(err, repository.ErrInvalidPage) {
+        fail(ctx, http.StatusBadRequest, err.Error())
+        return
+    }
+    logrus.Error(err)
+    fail(ctx, http.StatusInternalServerError, message)
+}
+
+// cargoFilter - груз для фильтра каталога из параметров weight, length, width, height
+// (value — чтение параметра запроса или формы). nil — груз не задан.
+func cargoFilter(value func(string) string) []calculator.CargoPiece {
+    piece := calculator.CargoPiece{Quantity: 1}
+    set := false
+    for name, dst := range map[string]*float64{
+        "weight": &piece.Weight, "length": &piece.Length, "width": &piece.Width, "height": &piece.Height,
+    } {
+        if parsed, err := strconv.ParseFloat(value(name), 64); err == nil && parsed > 0 {
+            *dst = parsed
+            set = true
+        }
+    }
+    if !set {
+        return nil
+    }
+    return []calculator.CargoPiece{piece}
+}
+
+// cargoFormValues - параметры груза для повторного заполнения формы поиска
+func cargoFormValues(value func(string) string) gin.H {
+    return gin.H{"weight": value("weight"), "length": value("length"), "width": value("width"), "height": value("height")}
+}
+
 // GetTransportServicesPage - главная страница со списком транспортных услуг
 func (h *Handler) GetTransportServicesPage(ctx *gin.Context) {
 	search := ctx.Query("search") // получаем параметр поиска из URL
 	
-	services, err := h.Repository.GetTransportServices(search)
+	// Только услуги, которые принимают груз (если указаны его вес и габариты)
+	services, err := h.Repository.GetTransportServices(search, cargoFilter(ctx.Query))
 	if err != nil {
 		logrus.Error(err)
 		ctx.HTML(http.StatusInternalServerError, "error.html", gin.H{
@@ -53,6 +175,7 @@ func (h *Handler) GetTransportServicesPage(ctx *gin.Context) {
 	ctx.HTML(http.StatusOK, "index.html", gin.H{
 		"services": services,
 		"search":   search, // передаем поисковый запрос для сохранения в поле
+		"cargo":    cargoFormValues(ctx.Query),
 	})
 }
 
@@ -84,9 +207,10 @@ func (h *Handler) GetTransportServicePage(ctx *gin.Context) {
 
 // GetLogisticRequestDetailsPage - страница с деталями логистической заявки
 func (h *Handler) GetLogisticRequestDetailsPage(ctx *gin.Context) {
-	// Получаем первую сформированную заявку для демонстрации
-	logisticRequests, err := h.Repository.GetLogisticRequests("formed", nil, nil)
-	if err != nil || len(logisticRequests) == 0 {
+	// Получаем последнюю сформированную заявку для демонстрации
+	logisticRequests, err := h.Repository.ListLogisticRequests(
+		repository.LogisticRequestFilter{Status: ds.StatusFormed}, repository.PageRequest{Limit: 1})
+	if err != nil || len(logisticRequests.Items) == 0 {
 		logrus.Error(err)
 		ctx.HTML(http.StatusInternalServerError, "error.html", gin.H{
 			"error": "Ошибка загрузки заявки",
@@ -94,7 +218,7 @@ func (h *Handler) GetLogisticRequestDetailsPage(ctx *gin.Context) {
 		return
 	}
 
-	logisticRequest := logisticRequests[0]
+	logisticRequest := logisticRequests.Items[0]
 	ctx.HTML(http.StatusOK, "logistic_request.html", gin.H{
 		"logistic_request": logisticRequest,
 		"services":         logisticRequest.Services,
@@ -104,7 +228,7 @@ func (h *Handler) GetLogisticRequestDetailsPage(ctx *gin.Context) {
 // GetDeliveryQuotePage - страница расчёта стоимости/сроков грузоперевозки
 func (h *Handler) GetDeliveryQuotePage(ctx *gin.Context) {
 	// Получаем услуги из черновика логистической заявки
-	draftServices, err := h.Repository.GetGuestDraftLogisticRequestServices()
+	draftServices, err := h.Repository.GetGuestDraftLogisticRequestServices(middleware.GetGuestSessionID(ctx))
 	if err != nil {
 		logrus.Errorf("Error getting draft logistic request services: %v", err)
 		draftServices = []ds.TransportService{}
@@ -168,86 +292,6 @@ func (h *Handler) PostDeliveryQuote(ctx *gin.Context) {
 	})
 }
 
-// calculateDistance - простая функция расчета расстояния между городами
-func calculateDistance(fromCity, toCity string) float64 {
-	// Приводим к нижнему регистру для сравнения
-	from := strings.ToLower(strings.TrimSpace(fromCity))
-	to := strings.ToLower(strings.TrimSpace(toCity))
-	
-	// Если города одинаковые
-	if from == to {
-		return 0
-	}
-	
-	// Простая база данных расстояний между основными городами
-	distances := map[string]map[string]float64{
-		"москва": {
-			"санкт-петербург": 635,
-			"спб":             635,
-			"екатеринбург":    1416,
-			"новосибирск":     3354,
-			"красноярск":      4205,
-			"иркутск":         5152,
-			"владивосток":     9100,
-			"ростов-на-дону":  1070,
-			"сочи":            1360,
-			"казань":          820,
-			"нижний новгород": 420,
-			"самара":          1050,
-			"волгоград":       970,
-			"воронеж":         520,
-			"саратов":         850,
-			"пермь":           1380,
-			"уфа":             1160,
-			"челябинск":       1510,
-			"омск":            2550,
-			"тюмень":          1720,
-		},
-		"санкт-петербург": {
-			"спб":             0,
-			"москва":          635,
-			"екатеринбург":    1780,
-			"новосибирск":     3720,
-			"калининград":     550,
-			"мурманск":        1050,
-			"архангельск":     1130,
-			"петрозаводск":    320,
-			"великий новгород": 180,
-		},
-		"екатеринбург": {
-			"москва":          1416,
-			"санкт-петербург": 1780,
-			"спб":             1780,
-			"новосибирск":     1940,
-			"челябинск":       200,
-			"пермь":           360,
-			"тюмень":          320,
-			"уфа":             520,
-		},
-		"новосибирск": {
-			"москва":          3354,
-			"санкт-петербург": 3720,
-			"спб":             3720,
-			"екатеринбург":    1940,
-			"омск":            650,
-			"красноярск":      850,
-			"томск":           270,
-			"барнаул":         230,
-		},
-	}
-	
-	// Ищем расстояние в базе данных
-	if cityDistances, exists := distances[from]; exists {
-		if distance, found := cityDistances[to]; found {
-			return distance
-		}
-	}
-	
-	// Если расстояние не найдено, используем примерную оценку
-	// Базовое расстояние для неизвестных маршрутов
-	return 500.0
-}
-
 // AddTransportServiceToDraftLogisticRequest - добавление услуги в черновик логистической заявки
 func (h *Handler) AddTransportServiceToDraftLogisticRequest(ctx *gin.Context) {
 	serviceIDStr := ctx.Param("service_id")
@@ -257,14 +301,14 @@ func (h *Handler) AddTransportServiceToDraftLogisticRequest(ctx *gin.Context) {
 		return
 	}
 
-    err = h.Repository.AddTransportServiceToGuestDraftLogisticRequest(serviceID)
+    err = h.Repository.AddTransportServiceToGuestDraftLogisticRequest(middleware.GetGuestSessionID(ctx), serviceID)
 	if err != nil {
         fail(ctx, http.StatusNotFound, err.Error())
 		return
 	}
 
 	// Возвращаем обновленное количество услуг в черновике
-	count := h.Repository.GetGuestDraftLogisticRequestServiceCount()
+	count := h.Repository.GetGuestDraftLogisticRequestServiceCount(middleware.GetGuestSessionID(ctx))
 	ctx.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"count":   count,
@@ -274,7 +318,7 @@ func (h *Handler) AddTransportServiceToDraftLogisticRequest(ctx *gin.Context) {
 
 // ClearDraftLogisticRequest - очистка черновика логистической заявки (guest)
 func (h *Handler) ClearDraftLogisticRequest(ctx *gin.Context) {
-    h.Repository.ClearGuestDraftLogisticRequest()
+    h.Repository.ClearGuestDraftLogisticRequest(middleware.GetGuestSessionID(ctx))
 
     ctx.JSON(http.StatusOK, gin.H{
         "success": true,
@@ -285,13 +329,13 @@ func (h *Handler) ClearDraftLogisticRequest(ctx *gin.Context) {
 
 // GetDraftLogisticRequest - получение черновика логистической заявки (guest)
 func (h *Handler) GetDraftLogisticRequest(ctx *gin.Context) {
-    draftRequest, err := h.Repository.GetGuestDraftLogisticRequestView()
+    draftRequest, err := h.Repository.GetGuestDraftLogisticRequestView(middleware.GetGuestSessionID(ctx))
 	if err != nil {
         fail(ctx, http.StatusInternalServerError, "failed to get draft logistic request")
 		return
 	}
 
-    services, err := h.Repository.GetGuestDraftLogisticRequestServices()
+    services, err := h.Repository.GetGuestDraftLogisticRequestServices(middleware.GetGuestSessionID(ctx))
 	if err != nil {
         fail(ctx, http.StatusInternalServerError, "failed to get transport services in draft logistic request")
 		return
@@ -300,13 +344,13 @@ func (h *Handler) GetDraftLogisticRequest(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{
 		"draft_logistic_request": draftRequest,
 		"services": services,
-		"count":    h.Repository.GetGuestDraftLogisticRequestServiceCount(),
+		"count":    h.Repository.GetGuestDraftLogisticRequestServiceCount(middleware.GetGuestSessionID(ctx)),
 	})
 }
 
 // GetDraftLogisticRequestServiceCount - получение количества услуг в черновике заявки
 func (h *Handler) GetDraftLogisticRequestServiceCount(ctx *gin.Context) {
-	count := h.Repository.GetGuestDraftLogisticRequestServiceCount()
+	count := h.Repository.GetGuestDraftLogisticRequestServiceCount(middleware.GetGuestSessionID(ctx))
 	ctx.JSON(http.StatusOK, gin.H{"count": count})
 }
 
@@ -338,7 +382,7 @@ func (h *Handler) CalculateLogisticRequestQuote(ctx *gin.Context) {
 	}
 
     // Используем компонент калькулятора
-    calc := calculator.NewDeliveryCalculator()
+    calc := calculator.NewDeliveryCalculator(h.Repository)
     res := calc.CalculateDelivery(service, request.FromCity, request.ToCity, request.Length, request.Width, request.Height, request.Weight)
 
     if !res.IsValid {
@@ -352,9 +396,188 @@ func (h *Handler) CalculateLogisticRequestQuote(ctx *gin.Context) {
         "total_cost":    res.TotalCost,
         "distance":      res.Distance,
         "volume":        res.Volume,
+        "actual_weight":     res.ActualWeight,
+        "volumetric_weight": res.VolumetricWeight,
+        "chargeable_weight": res.ChargeableWeight,
+        "cost_breakdown": res.CostBreakdown,
+        "days_breakdown": res.DaysBreakdown,
     })
 }
 
+// CompareLogisticRequestQuotes - сравнение всех видов транспорта для одного груза и маршрута
+// @Summary Compare quotes across transport services
+// @Description Quote the cargo with every active transport service, mark cheapest, fastest and best value options
+// @Tags logistic-requests
+// @Accept json
+// @Produce json
+// @Param request body map[string]interface{} true "Cargo, route and optional cost_weight (0..1)"
+// @Success 200 {object} calculator.ComparisonResult "Quote options"
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Router /api/logistic-requests/quote/compare [post]
+func (h *Handler) CompareLogisticRequestQuotes(ctx *gin.Context) {
+	var request struct {
+		FromCity   string   `json:"from_city" binding:"required"`
+		ToCity     string   `json:"to_city" binding:"required"`
+		Length     float64  `json:"length"`
+		Width      float64  `json:"width"`
+		Height     float64  `json:"height"`
+		Weight     float64  `json:"weight"`
+		CostWeight *float64 `json:"cost_weight"` // вес стоимости в оценке "цена/качество", 0..1
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		fail(ctx, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	costWeight := calculator.DefaultCostWeight
+	if request.CostWeight != nil {
+		if *request.CostWeight < 0 || *request.CostWeight > 1 {
+			fail(ctx, http.StatusBadRequest, "cost_weight must be between 0 and 1")
+			return
+		}
+		costWeight = *request.CostWeight
+	}
+
+	// Неизвестный город — ошибка запроса, а не отказ каждого варианта
+	if _, err := h.Repository.Distance(request.FromCity, request.ToCity); err != nil {
+		fail(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	services, err := h.Repository.GetTransportServices("", nil)
+	if err != nil {
+		logrus.Error(err)
+		fail(ctx, http.StatusInternalServerError, "failed to get services")
+		return
+	}
+
+	calc := calculator.NewDeliveryCalculator(h.Repository)
+	res := calc.CompareServices(services, request.FromCity, request.ToCity, request.Length, request.Width, request.Height, request.Weight, costWeight)
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "ok", "comparison": res})
+}
+
+// routeLegRequest - плечо маршрута во входном JSON
+type routeLegRequest struct {
+	TransportServiceID int    `json:"service_id" binding:"required"`
+	FromCity           string `json:"from_city" binding:"required"`
+	ToCity             string `json:"to_city" binding:"required"`
+}
+
+// toRouteLegInputs - преобразование плеч запроса во входные данные репозитория
+func toRouteLegInputs(legs []routeLegRequest) []repository.RouteLegInput {
+	inputs := make([]repository.RouteLegInput, 0, len(legs))
+	for _, leg := range legs {
+		inputs = append(inputs, repository.RouteLegInput{
+			TransportServiceID: leg.TransportServiceID,
+			FromCity:           leg.FromCity,
+			ToCity:             leg.ToCity,
+		})
+	}
+	return inputs
+}
+
+// CalculateLogisticRequestRouteQuote - расчет мультимодального маршрута из нескольких плеч
+// @Summary Multimodal route quote
+// @Description Calculate cost and days for a route of legs with per-leg breakdown and transshipments
+// @Tags logistic-requests
+// @Accept json
+// @Produce json
+// @Param request body map[string]interface{} true "Route legs and cargo parameters"
+// @Success 200 {object} calculator.RouteResult "Route quote"
+// @Failure 400 {object} map[string]string "Invalid route or cargo"
+// @Router /api/logistic-requests/quote/route [post]
+func (h *Handler) CalculateLogisticRequestRouteQuote(ctx *gin.Context) {
+	var request struct {
+		Legs   []routeLegRequest `json:"legs" binding:"required,min=1,dive"`
+		Length float64           `json:"length"`
+		Width  float64           `json:"width"`
+		Height float64           `json:"height"`
+		Weight float64           `json:"weight"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		fail(ctx, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	pieces := []calculator.CargoPiece{
+		{Quantity: 1, Length: request.Length, Width: request.Width, Height: request.Height, Weight: request.Weight},
+	}
+	res, err := h.Repository.CalculateRoute(toRouteLegInputs(request.Legs), pieces)
+	if err != nil {
+		fail(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !res.IsValid {
+		fail(ctx, http.StatusBadRequest, res.ErrorMessage)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "ok", "route": res})
+}
+
+// SetLogisticRequestRoute - сохранение маршрута из нескольких плеч в заявке-черновике
+func (h *Handler) SetLogisticRequestRoute(ctx *gin.Context) {
+	idStr := ctx.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		fail(ctx, http.StatusBadRequest, "invalid logistic request id")
+		return
+	}
+
+	if _, _, ok := h.authorizeLogisticRequest(ctx, id, auth.PermRequestsWriteAny); !ok {
+		return
+	}
+
+	var request struct {
+		Legs []routeLegRequest `json:"legs" binding:"required,min=1,dive"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		fail(ctx, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	res, err := h.Repository.SetLogisticRequestRoute(id, toRouteLegInputs(request.Legs), h.actorID(ctx))
+	if err != nil {
+		fail(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "ok", "request_id": id, "route": res})
+}
+
+// GetCities - подсказки городов для автодополнения
+// @Summary City autocomplete
+// @Description Ranked city suggestions by name, alias or transliteration (case and ё insensitive)
+// @Tags cities
+// @Produce json
+// @Param query query string false "City name prefix"
+// @Param limit query int false "Max suggestions (default 10)"
+// @Success 200 {object} map[string]interface{} "City suggestions"
+// @Router /api/cities [get]
+func (h *Handler) GetCities(ctx *gin.Context) {
+	query := ctx.Query("query")
+
+	limit := 10
+	if limitStr := ctx.Query("limit"); limitStr != "" {
+		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+
+	suggestions, err := h.Repository.SearchCities(query, limit)
+	if err != nil {
+		logrus.Error(err)
+		fail(ctx, http.StatusInternalServerError, "failed to search cities")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "ok", "cities": suggestions})
+}
+
 // FormLogisticRequest - формирование заявки создателем (дата формирования)
 func (h *Handler) FormLogisticRequest(ctx *gin.Context) {
 	idStr := ctx.Param("id")
@@ -384,20 +607,47 @@ func (h *Handler) FormLogisticRequest(ctx *gin.Context) {
 		return
 	}
 
-	if request.Weight <= 0 || request.Length <= 0 || request.Width <= 0 || request.Height <= 0 {
-		fail(ctx, http.StatusBadRequest, "weight, length, width, height must be greater than 0")
+	_, actor, ok := h.authorizeLogisticRequest(ctx, id, auth.PermRequestsWriteAny)
+	if !ok {
 		return
 	}
 
-	err = h.Repository.FormLogisticRequest(id, request.FromCity, request.ToCity, request.Weight, request.Length, request.Width, request.Height)
+	// Параметры груза проверяет репозиторий: при наличии грузовых мест они берутся из мест
+	quote, err := h.Repository.FormLogisticRequest(id, actor, request.FromCity, request.ToCity, request.Weight, request.Length, request.Width, request.Height)
 	if err != nil {
-		fail(ctx, http.StatusBadRequest, err.Error())
+		failTransition(ctx, err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{
 		"status":  "ok",
 		"message": "Заявка успешно сформирована",
+		"quote":   quote,
+	})
+}
+
+// GetQuote - зафиксированный расчет стоимости заявки.
+// Расчет виден тем же, кому видна его заявка; чужой расчет — 404.
+func (h *Handler) GetQuote(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		fail(ctx, http.StatusBadRequest, "invalid quote id")
+		return
+	}
+
+	quote, err := h.Repository.GetQuote(id)
+	if err != nil {
+		fail(ctx, http.StatusNotFound, err.Error())
+		return
+	}
+	if _, _, ok := h.authorizeLogisticRequest(ctx, quote.LogisticRequestID, auth.PermRequestsReadAny); !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status":  "ok",
+		"quote":   quote,
+		"expired": quote.IsExpired(time.Now()),
 	})
 }
 
@@ -485,33 +735,66 @@ func (h *Handler) SearchTransportServices(ctx *gin.Context) {
 	searchQuery := ctx.PostForm("search_query")
 	transportType := ctx.PostForm("transport_type")
 	
-	// Если это JSON запрос, обрабатываем по-другому
+	// JSON API — постраничный вывод с фильтрами по виду транспорта и грузу
 	if ctx.GetHeader("Content-Type") == "application/json" {
 		var request struct {
-			SearchQuery   string `json:"search_query"`
-			TransportType string `json:"transport_type"`
+			SearchQuery   string  `json:"search_query"`
+			TransportType string  `json:"transport_type"`
+			Weight        float64 `json:"weight"`
+			Length        float64 `json:"length"`
+			Width         float64 `json:"width"`
+			Height        float64 `json:"height"`
+			Explain       bool    `json:"explain"`
 		}
 		
         if err := ctx.ShouldBindJSON(&request); err != nil {
             fail(ctx, http.StatusBadRequest, "invalid request body")
 			return
 		}
+		page, ok := pageRequest(ctx)
+		if !ok {
+			return
+		}
 		
-		searchQuery = request.SearchQuery
-		transportType = request.TransportType
+		cargo := cargoFilter(func(name string) string {
+			value := map[string]float64{
+				"weight": request.Weight, "length": request.Length, "width": request.Width, "height": request.Height,
+			}[name]
+			return strconv.FormatFloat(value, 'f', -1, 64)
+		})
+		filter := repository.TransportServiceFilter{
+			Search:        request.SearchQuery,
+			TransportType: request.TransportType,
+			Cargo:         cargo,
+		}
+		services, err := h.Repository.ListTransportServices(filter, page)
+		if err != nil {
+			failList(ctx, err, "failed to search transports")
+			return
+		}
+		response := gin.H{
+			"status":     "ok",
+			"transports": services.Items,
+			"count":      len(services.Items),
+			"page":       services.PageInfo,
+		}
+		if request.Explain && cargo != nil {
+			if response["rejected"], err = h.Repository.ExplainCargoFit(filter); err != nil {
+				failList(ctx, err, "failed to search transports")
+				return
+			}
+		}
+		ctx.JSON(http.StatusOK, response)
+		return
 	}
 	
-	// Поиск транспорта
-	services, err := h.Repository.GetTransportServices(searchQuery)
+	// Поиск транспорта, который принимает груз (если указаны его вес и габариты)
+	services, err := h.Repository.GetTransportServices(searchQuery, cargoFilter(ctx.PostForm))
 	if err != nil {
         logrus.Error(err)
-        if ctx.GetHeader("Content-Type") == "application/json" {
-            fail(ctx, http.StatusInternalServerError, "failed to search transports")
-        } else {
-			ctx.HTML(http.StatusInternalServerError, "error.html", gin.H{
-				"error": "Ошибка поиска транспорта",
-			})
-		}
+		ctx.HTML(http.StatusInternalServerError, "error.html", gin.H{
+			"error": "Ошибка поиска транспорта",
+		})
 		return
 	}
 
@@ -526,23 +809,16 @@ func (h *Handler) SearchTransportServices(ctx *gin.Context) {
 		services = filtered
 	}
 
-	// Возвращаем результат в зависимости от типа запроса
-	if ctx.GetHeader("Content-Type") == "application/json" {
-        ctx.JSON(http.StatusOK, gin.H{
-            "status": "ok",
-            "transports": services,
-            "count": len(services),
-        })
-	} else {
-		// Возвращаем HTML страницу с результатами
-		ctx.HTML(http.StatusOK, "index.html", gin.H{
-			"services": services,
-			"search":   searchQuery,
-		})
-	}
+	// Возвращаем HTML страницу с результатами
+	ctx.HTML(http.StatusOK, "index.html", gin.H{
+		"services": services,
+		"search":   searchQuery,
+		"cargo":    cargoFormValues(ctx.PostForm),
+	})
 }
 
-// UpdateLogisticRequestStatus - обновление статуса заказа через курсор
+// UpdateLogisticRequestStatus - смена статуса заявки по правилам жизненного цикла (workflow).
+// Принимает также устаревшие статусы pending и processing.
 func (h *Handler) UpdateLogisticRequestStatus(ctx *gin.Context) {
 	orderIDStr := ctx.Param("id")
     orderID, err := strconv.Atoi(orderIDStr)
@@ -553,7 +829,9 @@ func (h *Handler) UpdateLogisticRequestStatus(ctx *gin.Context) {
 
 	// Получаем новый статус из JSON
 	var request struct {
-		Status string `json:"status" binding:"required"`
+		Status        string `json:"status" binding:"required"`
+		AcceptReprice bool   `json:"accept_reprice"`
+		Comment       string `json:"comment"`
 	}
 
     if err := ctx.ShouldBindJSON(&request); err != nil {
@@ -561,34 +839,40 @@ func (h *Handler) UpdateLogisticRequestStatus(ctx *gin.Context) {
 		return
 	}
 
-	// Валидация статуса
-	validStatuses := []string{"pending", "processing", "shipped", "delivered", "cancelled"}
-	isValid := false
-	for _, status := range validStatuses {
-		if request.Status == status {
-			isValid = true
-			break
-		}
+	status, err := workflow.ParseStatus(request.Status)
+	if err != nil {
+		fail(ctx, http.StatusBadRequest, err.Error())
+		return
 	}
 
-    if !isValid {
-        fail(ctx, http.StatusBadRequest, "invalid status. allowed: pending, processing, shipped, delivered, cancelled")
+	// Видимость заявки; кто может выполнить переход, решает workflow
+	_, actor, ok := h.authorizeLogisticRequest(ctx, orderID, auth.PermRequestsReadAny)
+	if !ok {
 		return
 	}
 
-	// Обновляем статус через курсор
-    err = h.Repository.UpdateLogisticRequestStatusWithCursor(orderID, request.Status)
-	if err != nil {
-        logrus.Error(err)
-        fail(ctx, http.StatusInternalServerError, "failed to update logistic request status")
+	// Формирование требует параметров груза, завершение — расчета цены
+	switch status {
+	case ds.StatusFormed:
+		fail(ctx, http.StatusBadRequest, "use PUT /api/logistic-requests/:id/form to form a logistic request")
 		return
+	case ds.StatusCompleted, ds.StatusRejected:
+		if _, err := h.Repository.CompleteLogisticRequest(orderID, status, actor, request.AcceptReprice, request.Comment); err != nil {
+			failTransition(ctx, err)
+			return
+		}
+	default:
+		if _, err := h.Repository.TransitionLogisticRequest(orderID, status, actor, request.Comment); err != nil {
+			failTransition(ctx, err)
+			return
+		}
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{
 		"status":     "ok",
 		"message":    "Статус логистической заявки успешно обновлен",
 		"request_id": orderID,
-		"new_status": request.Status,
+		"new_status": status,
 	})
 }
 
@@ -618,7 +902,12 @@ func (h *Handler) UpdateTransportService(ctx *gin.Context) {
         fail(ctx, http.StatusBadRequest, "invalid service id")
         return
     }
-    var req ds.TransportService
+    // Тело накладывается на сохранённую услугу: поля, которых нет в запросе, не меняются
+    req, err := h.Repository.GetTransportService(id)
+    if err != nil {
+        fail(ctx, http.StatusNotFound, "service not found")
+        return
+    }
     if err := ctx.ShouldBindJSON(&req); err != nil {
         fail(ctx, http.StatusBadRequest, "invalid request body")
         return
@@ -640,12 +929,87 @@ func (h *Handler) DeleteTransportService(ctx *gin.Context) {
         return
     }
     if err := h.Repository.DeleteTransportService(id); err != nil {
-        fail(ctx, http.StatusInternalServerError, "failed to delete service")
+        fail(ctx, http.StatusNotFound, "service not found")
         return
     }
     ctx.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
 
+// RestoreTransportService - восстановление удалённой услуги (администратор)
+func (h *Handler) RestoreTransportService(ctx *gin.Context) {
+    id, err := strconv.Atoi(ctx.Param("id"))
+    if err != nil {
+        fail(ctx, http.StatusBadRequest, "invalid service id")
+        return
+    }
+    service, err := h.Repository.RestoreTransportService(id)
+    if err != nil {
+        fail(ctx, http.StatusBadRequest, err.Error())
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status": "ok", "transport_service": service})
+}
+
+// GetServiceTariffs - история версий тарифа услуги
+func (h *Handler) GetServiceTariffs(ctx *gin.Context) {
+    id, err := strconv.Atoi(ctx.Param("id"))
+    if err != nil {
+        fail(ctx, http.StatusBadRequest, "invalid service id")
+        return
+    }
+    if _, err := h.Repository.GetTransportService(id); err != nil {
+        fail(ctx, http.StatusNotFound, "service not found")
+        return
+    }
+    tariffs, err := h.Repository.GetServiceTariffs(id)
+    if err != nil {
+        fail(ctx, http.StatusInternalServerError, "failed to get tariffs")
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status": "ok", "tariffs": tariffs})
+}
+
+// ScheduleServiceTariff - планирование изменения тарифа услуги с будущей даты
+func (h *Handler) ScheduleServiceTariff(ctx *gin.Context) {
+    id, err := strconv.Atoi(ctx.Param("id"))
+    if err != nil {
+        fail(ctx, http.StatusBadRequest, "invalid service id")
+        return
+    }
+    body, err := ctx.GetRawData()
+    if err != nil {
+        fail(ctx, http.StatusBadRequest, "invalid request body")
+        return
+    }
+    var head struct {
+        ValidFrom time.Time `json:"valid_from"`
+    }
+    if err := json.Unmarshal(body, &head); err != nil || head.ValidFrom.IsZero() {
+        fail(ctx, http.StatusBadRequest, "valid_from is required")
+        return
+    }
+
+    // Новая версия наследует тариф, действующий на дату начала; меняются только переданные поля
+    req, err := h.Repository.ServiceTariffBase(id, head.ValidFrom)
+    if err != nil {
+        fail(ctx, http.StatusNotFound, "service not found")
+        return
+    }
+    if err := json.Unmarshal(body, &req); err != nil {
+        fail(ctx, http.StatusBadRequest, "invalid request body")
+        return
+    }
+    if err := h.Repository.ScheduleServiceTariff(id, &req); err != nil {
+        if errors.Is(err, repository.ErrTariffClash) {
+            fail(ctx, http.StatusConflict, err.Error())
+            return
+        }
+        fail(ctx, http.StatusBadRequest, err.Error())
+        return
+    }
+    ctx.JSON(http.StatusCreated, gin.H{"status": "ok", "tariff": req})
+}
+
 // GetTransportService - получение транспортной услуги JSON
 func (h *Handler) GetTransportService(ctx *gin.Context) {
     idStr := ctx.Param("id")
@@ -662,8 +1026,27 @@ func (h *Handler) GetTransportService(ctx *gin.Context) {
     ctx.JSON(http.StatusOK, gin.H{"status": "ok", "service": svc})
 }
 
-// GetTransportServices - получение всех транспортных услуг в JSON с фильтрацией
-// Поддерживает фильтрацию по search, minPrice, maxPrice, dateFrom, dateTo
+// queryFloat - необязательный числовой параметр запроса (некорректное значение игнорируется)
+func queryFloat(ctx *gin.Context, name string) *float64 {
+    if parsed, err := strconv.ParseFloat(ctx.Query(name), 64); err == nil {
+        return &parsed
+    }
+    return nil
+}
+
+// queryInt - необязательный целый параметр запроса (некорректное значение игнорируется)
+func queryInt(ctx *gin.Context, name string) *int {
+    if parsed, err := strconv.Atoi(ctx.Query(name)); err == nil {
+        return &parsed
+    }
+    return nil
+}
+
+// GetTransportServices - страница транспортных услуг в JSON с фильтрацией
+// Поддерживает полнотекстовый поиск search (с релевантностью и подсветкой), фильтрацию по minPrice, maxPrice,
+// dateFrom, dateTo, диапазонам maxWeightFrom/To, maxVolumeFrom/To, deliveryDaysFrom/To,
+// по грузу weight, length, width, height (с причинами отсева при explain=true),
+// постраничный вывод (limit/offset или cursor) и сортировку sort по price, delivery_days, created_at, relevance
 func (h *Handler) GetTransportServices(ctx *gin.Context) {
     // Получаем параметры запроса из URL
     search := ctx.Query("search")
@@ -700,15 +1083,46 @@ func (h *Handler) GetTransportServices(ctx *gin.Context) {
         }
     }
     
-    // Получаем отфильтрованные услуги из репозитория
-    services, err := h.Repository.GetTransportServicesWithFilters(search, minPrice, maxPrice, dateFrom, dateTo)
-    if err != nil {
-        logrus.Error("Error getting services:", err)
-        fail(ctx, http.StatusInternalServerError, "failed to get services")
+    withDeleted, ok := includeDeleted(ctx)
+    if !ok {
+        return
+    }
+    page, ok := pageRequest(ctx)
+    if !ok {
         return
     }
     
-    ctx.JSON(http.StatusOK, gin.H{"status": "ok", "transport_services": services})
+    // Получаем страницу отфильтрованных услуг из репозитория
+    filter := repository.TransportServiceFilter{
+        Search:           search,
+        MinPrice:         minPrice,
+        MaxPrice:         maxPrice,
+        DateFrom:         dateFrom,
+        DateTo:           dateTo,
+        MaxWeightFrom:    queryFloat(ctx, "maxWeightFrom"),
+        MaxWeightTo:      queryFloat(ctx, "maxWeightTo"),
+        MaxVolumeFrom:    queryFloat(ctx, "maxVolumeFrom"),
+        MaxVolumeTo:      queryFloat(ctx, "maxVolumeTo"),
+        DeliveryDaysFrom: queryInt(ctx, "deliveryDaysFrom"),
+        DeliveryDaysTo:   queryInt(ctx, "deliveryDaysTo"),
+        Cargo:            cargoFilter(ctx.Query),
+        IncludeDeleted:   withDeleted,
+    }
+    services, err := h.Repository.ListTransportServices(filter, page)
+    if err != nil {
+        failList(ctx, err, "failed to get services")
+        return
+    }
+    
+    response := gin.H{"status": "ok", "transport_services": services.Items, "page": services.PageInfo}
+    // explain=true — отсеянные по грузу услуги и нарушенные ограничения
+    if ctx.Query("explain") == "true" && filter.Cargo != nil {
+        if response["rejected"], err = h.Repository.ExplainCargoFit(filter); err != nil {
+            failList(ctx, err, "failed to get services")
+            return
+        }
+    }
+    ctx.JSON(http.StatusOK, response)
 }
 
 // ==================== ПОЛЬЗОВАТЕЛИ ====================
@@ -742,16 +1156,22 @@ func (h *Handler) RegisterUser(ctx *gin.Context) {
     // Заменяем пароль на хеш
     req.Password = string(hashedPassword)
 
-    response, err := h.AuthService.Register(req)
+    response, err := h.AuthService.Register(req, clientInfo(ctx))
     if err != nil {
         if err.Error() == "user with this login already exists" {
             fail(ctx, http.StatusConflict, "user with this login already exists")
             return
         }
+        if errors.Is(err, service.ErrInvalidInvite) {
+            fail(ctx, http.StatusBadRequest, err.Error())
+            return
+        }
         fail(ctx, http.StatusInternalServerError, err.Error())
         return
     }
 
+    h.mergeGuestDraft(ctx, response.User.ID)
+
     ctx.JSON(http.StatusCreated, gin.H{
         "status":         "success",
         "message":        "User registered successfully",
@@ -777,7 +1197,8 @@ func (h *Handler) GetUserProfile(ctx *gin.Context) {
     }
 
     user.Password = ""
-    ctx.JSON(http.StatusOK, gin.H{"status": "ok", "user": user})
+    // Права роли — интерфейс показывает только доступные действия
+    ctx.JSON(http.StatusOK, gin.H{"status": "ok", "user": user, "permissions": auth.Permissions(user.Role)})
 }
 
 // UpdateUserProfile - обновление профиля пользователя
@@ -858,15 +1279,38 @@ func (h *Handler) LoginUser(ctx *gin.Context) {
     }
 
     // Используем сервис авторизации для входа
-    response, err := h.AuthService.Login(req, user.Password)
+    response, err := h.AuthService.Login(req, user.Password, clientInfo(ctx))
+    if errors.Is(err, service.ErrUserDisabled) || errors.Is(err, service.ErrPasswordResetRequired) {
+        fail(ctx, http.StatusForbidden, err.Error())
+        return
+    }
     if err != nil {
         fail(ctx, http.StatusUnauthorized, err.Error())
         return
     }
 
+    h.mergeGuestDraft(ctx, user.ID)
+
     ctx.JSON(http.StatusOK, response)
 }
 
+// clientInfo - устройство пользователя для списка сессий
+func clientInfo(ctx *gin.Context) service.ClientInfo {
+    return service.ClientInfo{UserAgent: ctx.Request.UserAgent(), IP: ctx.ClientIP()}
+}
+
+// mergeGuestDraft - перенос гостевого черновика в черновик пользователя после входа/регистрации.
+// Ошибка переноса не мешает входу.
+func (h *Handler) mergeGuestDraft(ctx *gin.Context, userID int) {
+    sessionID, ok := middleware.GuestSessionFromCookie(ctx)
+    if !ok {
+        return
+    }
+    if _, err := h.Repository.MergeGuestDraft(sessionID, userID); err != nil {
+        logrus.Errorf("failed to merge guest draft into user %d draft: %v", userID, err)
+    }
+}
+
 // LogoutUser - деавторизация
 // @Summary User logout
 // @Description Logout user and invalidate tokens
@@ -878,26 +1322,14 @@ func (h *Handler) LoginUser(ctx *gin.Context) {
 // @Failure 401 {object} map[string]string "Unauthorized"
 // @Router /logout [post]
 func (h *Handler) LogoutUser(ctx *gin.Context) {
-    userUUID, exists := middleware.GetUserUUID(ctx)
+    // Токен уже проверен RequireAuth
+    claims, exists := middleware.GetTokenClaims(ctx)
     if !exists {
         fail(ctx, http.StatusUnauthorized, "user not authenticated")
         return
     }
 
-    // Извлекаем токен из заголовка
-    authHeader := ctx.GetHeader("Authorization")
-    if authHeader == "" {
-        fail(ctx, http.StatusUnauthorized, "authorization header required")
-        return
-    }
-
-    token := strings.TrimPrefix(authHeader, "Bearer ")
-    if token == authHeader {
-        fail(ctx, http.StatusUnauthorized, "invalid authorization header format")
-        return
-    }
-
-    err := h.AuthService.Logout(userUUID, token)
+    err := h.AuthService.Logout(claims)
     if err != nil {
         fail(ctx, http.StatusInternalServerError, "failed to logout")
         return
@@ -930,7 +1362,7 @@ func (h *Handler) RefreshToken(ctx *gin.Context) {
         return
     }
 
-    response, err := h.AuthService.RefreshTokens(req.RefreshToken)
+    response, err := h.AuthService.RefreshTokens(req.RefreshToken, clientInfo(ctx))
     if err != nil {
         fail(ctx, http.StatusUnauthorized, err.Error())
         return
@@ -946,11 +1378,404 @@ func (h *Handler) RefreshToken(ctx *gin.Context) {
     })
 }
 
+// GetUserSessions - активные сессии (устройства) текущего пользователя
+// @Summary List active sessions
+// @Description Active sessions of the current user, one per login; current marks the session of the request token
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Sessions"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Router /api/users/sessions [get]
+func (h *Handler) GetUserSessions(ctx *gin.Context) {
+    actor, ok := h.currentActor(ctx)
+    if !ok {
+        return
+    }
+    sessions, err := h.Repository.GetUserSessions(actor.UserID)
+    if err != nil {
+        logrus.Error(err)
+        fail(ctx, http.StatusInternalServerError, "failed to get sessions")
+        return
+    }
+    current, _ := middleware.GetSessionID(ctx)
+    for i := range sessions {
+        sessions[i].Current = sessions[i].ID == current
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status": "ok", "sessions": sessions})
+}
+
+// DeleteUserSessions - завершение сессий текущего пользователя: одной (/sessions/:id) или всех, кроме текущей.
+// Refresh токены завершённой сессии больше не обмениваются, её access токены отклоняются.
+// @Summary Revoke sessions
+// @Description Revoke one session by id, or all sessions except the current one when id is omitted
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Param id path string false "Session ID"
+// @Success 200 {object} map[string]string "Sessions revoked"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Session not found"
+// @Router /api/users/sessions/{id} [delete]
+func (h *Handler) DeleteUserSessions(ctx *gin.Context) {
+    actor, ok := h.currentActor(ctx)
+    if !ok {
+        return
+    }
+
+    if id := ctx.Param("id"); id != "" {
+        if err := h.AuthService.RevokeSession(actor.UserID, id); err != nil {
+            if errors.Is(err, repository.ErrSessionNotFound) {
+                fail(ctx, http.StatusNotFound, "session not found")
+                return
+            }
+            logrus.Error(err)
+            fail(ctx, http.StatusInternalServerError, "failed to revoke session")
+            return
+        }
+        ctx.JSON(http.StatusOK, gin.H{"status": "ok"})
+        return
+    }
+
+    current, _ := middleware.GetSessionID(ctx)
+    if err := h.AuthService.RevokeOtherSessions(actor.UserID, current); err != nil {
+        logrus.Error(err)
+        fail(ctx, http.StatusInternalServerError, "failed to revoke sessions")
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// GetJWKS - открытые ключи подписи токенов (JWKS) для проверки токенов другими сервисами
+// @Summary JSON Web Key Set
+// @Description Public keys (RS256/EdDSA, by kid) that verify access and refresh tokens, including keys scheduled for rotation
+// @Tags auth
+// @Produce json
+// @Success 200 {object} auth.JWKS "Key set"
+// @Router /.well-known/jwks.json [get]
+func (h *Handler) GetJWKS(ctx *gin.Context) {
+    ctx.Header("Cache-Control", "public, max-age=300")
+    ctx.JSON(http.StatusOK, h.AuthService.JWKS())
+}
+
+// RevokeUserTokens - отзыв всех токенов пользователя (для администратора)
+// @Summary Revoke all tokens of a user
+// @Description Bumps the user's token generation: every access and refresh token issued before becomes invalid
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]interface{} "Tokens revoked"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "User not found"
+// @Router /api/admin/users/{id}/revoke-tokens [post]
+func (h *Handler) RevokeUserTokens(ctx *gin.Context) {
+    id, err := strconv.Atoi(ctx.Param("id"))
+    if err != nil {
+        fail(ctx, http.StatusBadRequest, "invalid user id")
+        return
+    }
+    user, err := h.Repository.GetUser(id)
+    if err != nil {
+        fail(ctx, http.StatusNotFound, "user not found")
+        return
+    }
+    generation, err := h.AuthService.RevokeAllTokens(user)
+    if err != nil {
+        logrus.Error(err)
+        fail(ctx, http.StatusInternalServerError, "failed to revoke tokens")
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status": "ok", "token_generation": generation})
+}
+
+// adminTargetUser - пользователь из пути для действия администратора.
+// notSelf - действие нельзя применить к себе (смена своей роли, блокировка себя).
+func (h *Handler) adminTargetUser(ctx *gin.Context, notSelf bool) (ds.User, workflow.Actor, bool) {
+    actor, ok := h.currentActor(ctx)
+    if !ok {
+        return ds.User{}, workflow.Actor{}, false
+    }
+    id, err := strconv.Atoi(ctx.Param("id"))
+    if err != nil {
+        fail(ctx, http.StatusBadRequest, "invalid user id")
+        return ds.User{}, workflow.Actor{}, false
+    }
+    if notSelf && id == actor.UserID {
+        fail(ctx, http.StatusBadRequest, "administrators cannot apply this action to themselves")
+        return ds.User{}, workflow.Actor{}, false
+    }
+    user, err := h.Repository.GetUser(id)
+    if err != nil {
+        fail(ctx, http.StatusNotFound, "user not found")
+        return ds.User{}, workflow.Actor{}, false
+    }
+    return user, actor, true
+}
+
+// GetUsers - список пользователей с поиском (для администратора)
+// @Summary List users
+// @Description Paged list of users; search matches login, name or email, filters by role and disabled state
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param search query string false "Part of login, name or email"
+// @Param role query string false "Role (buyer, manager, admin)"
+// @Param disabled query bool false "Only disabled (true) or only active (false) users"
+// @Param limit query int false "Page size"
+// @Param offset query int false "Offset"
+// @Param cursor query string false "Cursor of the next page"
+// @Param sort query string false "Sort field: created_at, login (prefix - for descending)"
+// @Success 200 {object} map[string]interface{} "Users"
+// @Failure 400 {object} map[string]string "Invalid filter or page"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /api/admin/users [get]
+func (h *Handler) GetUsers(ctx *gin.Context) {
+    page, ok := pageRequest(ctx)
+    if !ok {
+        return
+    }
+    filter := repository.UserFilter{Search: ctx.Query("search"), Role: ctx.Query("role")}
+    if filter.Role != "" && !ds.Roles[filter.Role] {
+        fail(ctx, http.StatusBadRequest, "unknown role")
+        return
+    }
+    if value := ctx.Query("disabled"); value != "" {
+        disabled, err := strconv.ParseBool(value)
+        if err != nil {
+            fail(ctx, http.StatusBadRequest, "invalid disabled")
+            return
+        }
+        filter.Disabled = &disabled
+    }
+
+    users, err := h.Repository.ListUsers(filter, page)
+    if err != nil {
+        failList(ctx, err, "failed to get users")
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status": "ok", "users": users.Items, "page": users.PageInfo})
+}
+
+// UpdateUserRole - смена роли пользователя (для администратора); токены пользователя отзываются
+// @Summary Change user role
+// @Description Sets the role of a user and revokes their tokens so the new role applies immediately
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param body body map[string]string true "New role: buyer, manager or admin"
+// @Success 200 {object} map[string]interface{} "Updated user"
+// @Failure 400 {object} map[string]string "Unknown role or own account"
+// @Failure 404 {object} map[string]string "User not found"
+// @Router /api/admin/users/{id}/role [put]
+func (h *Handler) UpdateUserRole(ctx *gin.Context) {
+    var req struct {
+        Role string `json:"role" binding:"required"`
+    }
+    if err := ctx.ShouldBindJSON(&req); err != nil {
+        fail(ctx, http.StatusBadRequest, "invalid request body")
+        return
+    }
+    user, _, ok := h.adminTargetUser(ctx, true)
+    if !ok {
+        return
+    }
+
+    user, err := h.AuthService.ChangeUserRole(user, req.Role)
+    if errors.Is(err, service.ErrUnknownRole) {
+        fail(ctx, http.StatusBadRequest, err.Error())
+        return
+    }
+    if err != nil {
+        logrus.Error(err)
+        fail(ctx, http.StatusInternalServerError, "failed to change role")
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status": "ok", "user": user})
+}
+
+// DisableUser - блокировка пользователя (для администратора): вход запрещен, токены отозваны
+// @Summary Disable user
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]interface{} "Disabled user"
+// @Failure 400 {object} map[string]string "Own account"
+// @Failure 404 {object} map[string]string "User not found"
+// @Router /api/admin/users/{id}/disable [post]
+func (h *Handler) DisableUser(ctx *gin.Context) {
+    h.setUserDisabled(ctx, true)
+}
+
+// EnableUser - разблокировка пользователя (для администратора)
+// @Summary Enable user
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]interface{} "Enabled user"
+// @Failure 404 {object} map[string]string "User not found"
+// @Router /api/admin/users/{id}/enable [post]
+func (h *Handler) EnableUser(ctx *gin.Context) {
+    h.setUserDisabled(ctx, false)
+}
+
+// setUserDisabled - блокировка или разблокировка пользователя из пути
+func (h *Handler) setUserDisabled(ctx *gin.Context, disabled bool) {
+    user, _, ok := h.adminTargetUser(ctx, disabled)
+    if !ok {
+        return
+    }
+    user, err := h.AuthService.SetUserDisabled(user, disabled)
+    if err != nil {
+        logrus.Error(err)
+        fail(ctx, http.StatusInternalServerError, "failed to update user")
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status": "ok", "user": user})
+}
+
+// ForceUserPasswordReset - принудительный сброс пароля (для администратора).
+// Пароль перестает действовать, токены отзываются; токен сброса передается пользователю вне API.
+// @Summary Force password reset
+// @Description Blocks login until the user sets a new password with the returned one-time token
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 201 {object} map[string]interface{} "One-time reset token"
+// @Failure 404 {object} map[string]string "User not found"
+// @Router /api/admin/users/{id}/password-reset [post]
+func (h *Handler) ForceUserPasswordReset(ctx *gin.Context) {
+    user, actor, ok := h.adminTargetUser(ctx, false)
+    if !ok {
+        return
+    }
+    token, reset, err := h.AuthService.ForcePasswordReset(user, actor.UserID)
+    if err != nil {
+        logrus.Error(err)
+        fail(ctx, http.StatusInternalServerError, "failed to reset password")
+        return
+    }
+    ctx.JSON(http.StatusCreated, gin.H{"status": "ok", "reset_token": token, "expires_at": reset.ExpiresAt})
+}
+
+// CreateManagerInvite - приглашение менеджера (для администратора).
+// Зарегистрироваться по приглашению можно один раз и только с указанным email.
+// @Summary Invite a manager
+// @Description Creates a one-time invite token; /sign_up with this invite_token and email creates a manager
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body map[string]string true "Email of the invited manager"
+// @Success 201 {object} map[string]interface{} "One-time invite token"
+// @Failure 400 {object} map[string]string "Invalid email"
+// @Router /api/admin/invites [post]
+func (h *Handler) CreateManagerInvite(ctx *gin.Context) {
+    var req struct {
+        Email string `json:"email" binding:"required,email"`
+    }
+    if err := ctx.ShouldBindJSON(&req); err != nil {
+        fail(ctx, http.StatusBadRequest, "invalid request body")
+        return
+    }
+    actor, ok := h.currentActor(ctx)
+    if !ok {
+        return
+    }
+    token, invite, err := h.AuthService.CreateManagerInvite(req.Email, actor.UserID)
+    if err != nil {
+        logrus.Error(err)
+        fail(ctx, http.StatusInternalServerError, "failed to create invite")
+        return
+    }
+    ctx.JSON(http.StatusCreated, gin.H{"status": "ok", "invite_token": token, "invite": invite})
+}
+
+// ResetPassword - установка нового пароля по одноразовому токену сброса
+// @Summary Reset password
+// @Description Sets a new password using the token from a forced password reset
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param body body map[string]string true "token and new password"
+// @Success 200 {object} map[string]string "Password changed"
+// @Failure 400 {object} map[string]string "Invalid or expired token"
+// @Router /password/reset [post]
+func (h *Handler) ResetPassword(ctx *gin.Context) {
+    var req struct {
+        Token    string `json:"token" binding:"required"`
+        Password string `json:"password" binding:"required,min=6"`
+    }
+    if err := ctx.ShouldBindJSON(&req); err != nil {
+        fail(ctx, http.StatusBadRequest, "invalid request body")
+        return
+    }
+    hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+    if err != nil {
+        fail(ctx, http.StatusInternalServerError, "failed to hash password")
+        return
+    }
+    err = h.AuthService.ResetPassword(req.Token, string(hashedPassword))
+    if errors.Is(err, service.ErrInvalidResetToken) {
+        fail(ctx, http.StatusBadRequest, err.Error())
+        return
+    }
+    if err != nil {
+        logrus.Error(err)
+        fail(ctx, http.StatusInternalServerError, "failed to reset password")
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
 // ==================== ЛОГИСТИЧЕСКИЕ ЗАЯВКИ ====================
 
+// logisticRequestFilterParams - параметры фильтра списка заявок, которые можно сохранить в наборе
+var logisticRequestFilterParams = map[string]bool{
+    "status": true, "date_from": true, "date_to": true, "creator": true, "moderator": true,
+    "from_city": true, "to_city": true, "service_id": true, "min_cost": true, "max_cost": true,
+    "min_days": true, "max_days": true, "q": true,
+}
+
+// logisticRequestFilter - фильтр списка заявок из параметров (param — значение параметра по имени).
+// Некорректные даты и числа игнорируются.
+func logisticRequestFilter(param func(string) string) repository.LogisticRequestFilter {
+    filter := repository.LogisticRequestFilter{
+        Status:    param("status"),
+        Creator:   param("creator"),
+        Moderator: param("moderator"),
+        FromCity:  param("from_city"),
+        ToCity:    param("to_city"),
+        Comment:   param("q"),
+    }
+    for name, dst := range map[string]**time.Time{"date_from": &filter.DateFrom, "date_to": &filter.DateTo} {
+        if t, err := time.Parse("2006-01-02", param(name)); err == nil {
+            *dst = &t
+        }
+    }
+    for name, dst := range map[string]**float64{"min_cost": &filter.MinCost, "max_cost": &filter.MaxCost} {
+        if parsed, err := strconv.ParseFloat(param(name), 64); err == nil {
+            *dst = &parsed
+        }
+    }
+    for name, dst := range map[string]**int{"min_days": &filter.MinDays, "max_days": &filter.MaxDays} {
+        if parsed, err := strconv.Atoi(param(name)); err == nil {
+            *dst = &parsed
+        }
+    }
+    filter.ServiceID, _ = strconv.Atoi(param("service_id"))
+    return filter
+}
+
 // GetLogisticRequests - получение списка логистических заявок с фильтрацией
 // @Summary Get logistic requests list
-// @Description Get logistic requests list with filtering by status and date range
+// @Description Get logistic requests list with filtering by status, dates, creator, moderator, cities, service, cost, days and comments.
+// @Description preset applies a saved filter preset; explicit parameters override its values.
 // @Tags logistic-requests
 // @Accept json
 // @Produce json
@@ -958,60 +1783,136 @@ func (h *Handler) RefreshToken(ctx *gin.Context) {
 // @Param status query string false "Logistic request status filter"
 // @Param date_from query string false "Date from (YYYY-MM-DD)"
 // @Param date_to query string false "Date to (YYYY-MM-DD)"
+// @Param creator query string false "Creator login or name (substring)"
+// @Param moderator query string false "Moderator login or name (substring)"
+// @Param from_city query string false "Departure city"
+// @Param to_city query string false "Destination city"
+// @Param service_id query int false "Transport service included in the request"
+// @Param min_cost query number false "Minimal total cost"
+// @Param max_cost query number false "Maximal total cost"
+// @Param min_days query int false "Minimal total days"
+// @Param max_days query int false "Maximal total days"
+// @Param q query string false "Text in line or history comments"
+// @Param preset query int false "Saved filter preset ID"
 // @Success 200 {object} map[string]interface{} "Logistic requests retrieved successfully"
 // @Failure 401 {object} map[string]string "Unauthorized"
 // @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Preset not found"
 // @Router /api/logistic-requests [get]
 func (h *Handler) GetLogisticRequests(ctx *gin.Context) {
-    userUUID, exists := middleware.GetUserUUID(ctx)
-    if !exists {
-        fail(ctx, http.StatusUnauthorized, "authentication required")
+    actor, ok := h.currentActor(ctx)
+    if !ok {
         return
     }
 
-    userRole, _ := middleware.GetUserRole(ctx)
-    status := ctx.Query("status")
-    dateFromStr := ctx.Query("date_from")
-    dateToStr := ctx.Query("date_to")
-
-    var dateFrom, dateTo *time.Time
-    if dateFromStr != "" {
-        if t, err := time.Parse("2006-01-02", dateFromStr); err == nil {
-            dateFrom = &t
+    // Значения из сохранённого набора, явные параметры запроса важнее
+    preset := map[string]string{}
+    if presetID := ctx.Query("preset"); presetID != "" {
+        id, err := strconv.Atoi(presetID)
+        if err != nil {
+            fail(ctx, http.StatusBadRequest, "invalid preset id")
+            return
         }
+        saved, err := h.Repository.GetFilterPreset(actor.UserID, id)
+        if err != nil {
+            fail(ctx, http.StatusNotFound, err.Error())
+            return
+        }
+        preset = saved.Filters
     }
-    if dateToStr != "" {
-        if t, err := time.Parse("2006-01-02", dateToStr); err == nil {
-            dateTo = &t
+    param := func(name string) string {
+        if value, ok := ctx.GetQuery(name); ok {
+            return value
         }
+        return preset[name]
+    }
+
+    withDeleted, ok := includeDeleted(ctx)
+    if !ok {
+        return
+    }
+    page, ok := pageRequest(ctx)
+    if !ok {
+        return
+    }
+
+    filter := logisticRequestFilter(param)
+    filter.IncludeDeleted = withDeleted
+
+    // Без права на чужие заявки (buyer) — только свои
+    if !auth.HasPermission(actor.Role, auth.PermRequestsReadAny) {
+        filter.CreatorID = actor.UserID
     }
 
-    logisticRequests, err := h.Repository.GetLogisticRequests(status, dateFrom, dateTo)
+    logisticRequests, err := h.Repository.ListLogisticRequests(filter, page)
     if err != nil {
-        fail(ctx, http.StatusInternalServerError, "failed to get logistic requests")
+        failList(ctx, err, "failed to get logistic requests")
         return
     }
 
-    // Фильтрация по ролям
-    if userRole == ds.RoleBuyer {
-        // Buyer видит только свои заявки
-        user, err := h.Repository.GetUserByUUID(userUUID)
-        if err != nil {
-            fail(ctx, http.StatusInternalServerError, "failed to get user")
+    ctx.JSON(http.StatusOK, gin.H{"status": "ok", "logistic_requests": logisticRequests.Items, "page": logisticRequests.PageInfo})
+}
+
+// GetFilterPresets - сохранённые наборы фильтров списка заявок текущего пользователя
+func (h *Handler) GetFilterPresets(ctx *gin.Context) {
+    actor, ok := h.currentActor(ctx)
+    if !ok {
+        return
+    }
+    presets, err := h.Repository.GetFilterPresets(actor.UserID)
+    if err != nil {
+        logrus.Error(err)
+        fail(ctx, http.StatusInternalServerError, "failed to get filter presets")
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status": "ok", "presets": presets})
+}
+
+// SaveFilterPreset - сохранение набора фильтров (набор с тем же именем заменяется)
+func (h *Handler) SaveFilterPreset(ctx *gin.Context) {
+    actor, ok := h.currentActor(ctx)
+    if !ok {
+        return
+    }
+    var request struct {
+        Name    string            `json:"name" binding:"required"`
+        Filters map[string]string `json:"filters" binding:"required"`
+    }
+    if err := ctx.ShouldBindJSON(&request); err != nil {
+        fail(ctx, http.StatusBadRequest, "invalid request body")
+        return
+    }
+    for name := range request.Filters {
+        if !logisticRequestFilterParams[name] {
+            fail(ctx, http.StatusBadRequest, "unknown filter: "+name)
             return
         }
-        
-        var userLogisticRequests []ds.LogisticRequest
-        for _, lr := range logisticRequests {
-            if lr.CreatorID == user.ID {
-                userLogisticRequests = append(userLogisticRequests, lr)
-            }
-        }
-        logisticRequests = userLogisticRequests
     }
-    // Manager и Admin видят все заявки
 
-    ctx.JSON(http.StatusOK, gin.H{"status": "ok", "logistic_requests": logisticRequests})
+    preset := ds.FilterPreset{UserID: actor.UserID, Name: strings.TrimSpace(request.Name), Filters: request.Filters}
+    if err := h.Repository.SaveFilterPreset(&preset); err != nil {
+        fail(ctx, http.StatusBadRequest, err.Error())
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status": "ok", "preset": preset})
+}
+
+// DeleteFilterPreset - удаление набора фильтров текущего пользователя
+func (h *Handler) DeleteFilterPreset(ctx *gin.Context) {
+    id, err := strconv.Atoi(ctx.Param("id"))
+    if err != nil {
+        fail(ctx, http.StatusBadRequest, "invalid preset id")
+        return
+    }
+    actor, ok := h.currentActor(ctx)
+    if !ok {
+        return
+    }
+    if err := h.Repository.DeleteFilterPreset(actor.UserID, id); err != nil {
+        fail(ctx, http.StatusNotFound, err.Error())
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
 
 // GetLogisticRequest - получение заявки по ID
@@ -1023,13 +1924,39 @@ func (h *Handler) GetLogisticRequest(ctx *gin.Context) {
         return
     }
 
-    logisticRequest, err := h.Repository.GetLogisticRequest(id)
+    logisticRequest, actor, ok := h.authorizeLogisticRequest(ctx, id, auth.PermRequestsReadAny)
+    if !ok {
+        return
+    }
+
+    ctx.JSON(http.StatusOK, gin.H{
+        "status":           "ok",
+        "logistic_request": logisticRequest,
+        // Статусы, в которые текущий пользователь может перевести заявку
+        "available_statuses": workflow.Available(logisticRequest, actor),
+    })
+}
+
+// GetLogisticRequestHistory - журнал изменений заявки (статусы, услуги, места, маршрут, поля)
+func (h *Handler) GetLogisticRequestHistory(ctx *gin.Context) {
+    id, err := strconv.Atoi(ctx.Param("id"))
     if err != nil {
-        fail(ctx, http.StatusNotFound, "logistic request not found")
+        fail(ctx, http.StatusBadRequest, "invalid logistic request id")
         return
     }
 
-    ctx.JSON(http.StatusOK, gin.H{"status": "ok", "logistic_request": logisticRequest})
+    if _, _, ok := h.authorizeLogisticRequest(ctx, id, auth.PermRequestsReadAny); !ok {
+        return
+    }
+
+    events, err := h.Repository.GetLogisticRequestEvents(id)
+    if err != nil {
+        logrus.Error(err)
+        fail(ctx, http.StatusInternalServerError, "failed to get logistic request history")
+        return
+    }
+
+    ctx.JSON(http.StatusOK, gin.H{"status": "ok", "request_id": id, "events": events})
 }
 
 // UpdateLogisticRequest - обновление заявки
@@ -1055,9 +1982,8 @@ func (h *Handler) UpdateLogisticRequest(ctx *gin.Context) {
         return
     }
 
-    logisticRequest, err := h.Repository.GetLogisticRequest(id)
-    if err != nil {
-        fail(ctx, http.StatusNotFound, "logistic request not found")
+    logisticRequest, _, ok := h.authorizeLogisticRequest(ctx, id, auth.PermRequestsWriteAny)
+    if !ok {
         return
     }
 
@@ -1085,7 +2011,7 @@ func (h *Handler) UpdateLogisticRequest(ctx *gin.Context) {
         logisticRequest.Height = req.Height
     }
 
-    if err := h.Repository.UpdateLogisticRequest(&logisticRequest); err != nil {
+    if err := h.Repository.UpdateLogisticRequest(&logisticRequest, h.actorID(ctx)); err != nil {
         fail(ctx, http.StatusInternalServerError, "failed to update logistic request")
         return
     }
@@ -1107,6 +2033,7 @@ func (h *Handler) UpdateLogisticRequest(ctx *gin.Context) {
 // @Failure 400 {object} map[string]string "Invalid request"
 // @Failure 401 {object} map[string]string "Unauthorized"
 // @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 409 {object} map[string]interface{} "Quote expired and price changed"
 // @Router /api/logistic-requests/{id}/complete [put]
 func (h *Handler) CompleteLogisticRequest(ctx *gin.Context) {
     // Middleware уже проверил авторизацию и роль модератора
@@ -1118,7 +2045,9 @@ func (h *Handler) CompleteLogisticRequest(ctx *gin.Context) {
     }
 
     var req struct {
-        Status string `json:"status" binding:"required"`
+        Status        string `json:"status" binding:"required"`
+        AcceptReprice bool   `json:"accept_reprice"` // подтверждение новой цены после истечения расчета
+        Comment       string `json:"comment"`        // комментарий модератора для журнала
     }
 
     if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -1132,23 +2061,32 @@ func (h *Handler) CompleteLogisticRequest(ctx *gin.Context) {
         return
     }
 
-    // Получаем пользователя для moderatorID
-    userUUID, _ := middleware.GetUserUUID(ctx)
-    user, err := h.Repository.GetUserByUUID(userUUID)
-    if err != nil {
-        fail(ctx, http.StatusInternalServerError, "failed to get user")
+    // Модератор, выполняющий завершение
+    actor, ok := h.currentActor(ctx)
+    if !ok {
         return
     }
 
-    err = h.Repository.CompleteLogisticRequest(id, req.Status, user.ID)
+    pricing, err := h.Repository.CompleteLogisticRequest(id, req.Status, actor, req.AcceptReprice, req.Comment)
     if err != nil {
-        fail(ctx, http.StatusBadRequest, err.Error())
+        // Расчет истёк и цена изменилась — модератор видит разницу и повторяет запрос с accept_reprice
+        var expired *repository.QuoteExpiredError
+        if errors.As(err, &expired) {
+            ctx.JSON(http.StatusConflict, gin.H{
+                "status":  "fail",
+                "message": err.Error(),
+                "pricing": expired.Pricing,
+            })
+            return
+        }
+        failTransition(ctx, err)
         return
     }
 
     ctx.JSON(http.StatusOK, gin.H{
         "status":  "success",
         "message": "LogisticRequest completed successfully",
+        "pricing": pricing,
     })
 }
 
@@ -1161,9 +2099,14 @@ func (h *Handler) DeleteLogisticRequest(ctx *gin.Context) {
         return
     }
 
-    err = h.Repository.DeleteLogisticRequest(id)
+    _, actor, ok := h.authorizeLogisticRequest(ctx, id, auth.PermRequestsWriteAny)
+    if !ok {
+        return
+    }
+
+    err = h.Repository.DeleteLogisticRequest(id, actor)
     if err != nil {
-        fail(ctx, http.StatusInternalServerError, "failed to delete logistic request")
+        failTransition(ctx, err)
         return
     }
 
@@ -1172,13 +2115,13 @@ func (h *Handler) DeleteLogisticRequest(ctx *gin.Context) {
 
 // GetDraftLogisticRequestIcon - получение счетчика/ID черновика заявки (для иконки)
 func (h *Handler) GetDraftLogisticRequestIcon(ctx *gin.Context) {
-    draftRequest, err := h.Repository.GetGuestDraftLogisticRequestView()
+    draftRequest, err := h.Repository.GetGuestDraftLogisticRequestView(middleware.GetGuestSessionID(ctx))
     if err != nil {
         fail(ctx, http.StatusInternalServerError, "failed to get draft logistic request")
         return
     }
 
-    count := h.Repository.GetGuestDraftLogisticRequestServiceCount()
+    count := h.Repository.GetGuestDraftLogisticRequestServiceCount(middleware.GetGuestSessionID(ctx))
     ctx.JSON(http.StatusOK, gin.H{
 		"status":     "ok",
 		"request_id": draftRequest.ID,
@@ -1249,7 +2192,7 @@ func (h *Handler) AddTransportServiceToUserDraft(ctx *gin.Context) {
 		return
 	}
 
-	if err := h.Repository.AddServiceToLogisticRequest(orderID, serviceID); err != nil {
+	if err := h.Repository.AddServiceToLogisticRequest(orderID, serviceID, user.ID); err != nil {
 		fail(ctx, http.StatusBadRequest, err.Error())
 		return
 	}
@@ -1300,6 +2243,199 @@ func (h *Handler) ClearUserDraftLogisticRequest(ctx *gin.Context) {
 	})
 }
 
+// ==================== ОТСЛЕЖИВАНИЕ ====================
+
+// GetLogisticRequestTracking - отметки о движении груза и прогноз доставки по заявке
+func (h *Handler) GetLogisticRequestTracking(ctx *gin.Context) {
+    id, err := strconv.Atoi(ctx.Param("id"))
+    if err != nil {
+        fail(ctx, http.StatusBadRequest, "invalid logistic request id")
+        return
+    }
+
+    if _, _, ok := h.authorizeLogisticRequest(ctx, id, auth.PermRequestsReadAny); !ok {
+        return
+    }
+
+    tracking, err := h.Repository.GetTracking(id)
+    if err != nil {
+        fail(ctx, http.StatusNotFound, err.Error())
+        return
+    }
+
+    ctx.JSON(http.StatusOK, gin.H{"status": "ok", "tracking": tracking})
+}
+
+// GetTrackingByNumber - публичное отслеживание груза по номеру (без авторизации)
+func (h *Handler) GetTrackingByNumber(ctx *gin.Context) {
+    number := strings.ToUpper(strings.TrimSpace(ctx.Param("number")))
+    tracking, err := h.Repository.GetTrackingByNumber(number)
+    if err != nil {
+        fail(ctx, http.StatusNotFound, "tracking number not found")
+        return
+    }
+
+    ctx.JSON(http.StatusOK, gin.H{"status": "ok", "tracking": tracking})
+}
+
+// AddTrackingCheckpoint - отметка о движении груза (менеджер)
+func (h *Handler) AddTrackingCheckpoint(ctx *gin.Context) {
+    id, err := strconv.Atoi(ctx.Param("id"))
+    if err != nil {
+        fail(ctx, http.StatusBadRequest, "invalid logistic request id")
+        return
+    }
+
+    var req struct {
+        EventType  string     `json:"event_type" binding:"required"`
+        City       string     `json:"city" binding:"required"`
+        OccurredAt *time.Time `json:"occurred_at"`
+        Note       string     `json:"note"`
+    }
+    if err := ctx.ShouldBindJSON(&req); err != nil {
+        fail(ctx, http.StatusBadRequest, "invalid request body")
+        return
+    }
+
+    actor, ok := h.currentActor(ctx)
+    if !ok {
+        return
+    }
+
+    checkpoint := ds.TrackingCheckpoint{EventType: req.EventType, City: req.City, Note: req.Note}
+    if req.OccurredAt != nil {
+        checkpoint.OccurredAt = *req.OccurredAt
+    }
+    if err := h.Repository.AddTrackingCheckpoint(id, &checkpoint, actor); err != nil {
+        failTransition(ctx, err)
+        return
+    }
+
+    tracking, err := h.Repository.GetTracking(id)
+    if err != nil {
+        fail(ctx, http.StatusInternalServerError, "failed to get tracking")
+        return
+    }
+
+    ctx.JSON(http.StatusCreated, gin.H{"status": "ok", "checkpoint": checkpoint, "tracking": tracking})
+}
+
+// ==================== ГРУЗОВЫЕ МЕСТА ====================
+
+// cargoItemRequest - грузовое место во входном JSON
+type cargoItemRequest struct {
+    Quantity    int     `json:"quantity"`
+    Length      float64 `json:"length" binding:"required"`
+    Width       float64 `json:"width" binding:"required"`
+    Height      float64 `json:"height" binding:"required"`
+    Weight      float64 `json:"weight" binding:"required"`
+    Stackable   *bool   `json:"stackable"`
+    Fragile     bool    `json:"fragile"`
+    Description string  `json:"description"`
+}
+
+// toCargoItem - преобразование запроса в модель места (по умолчанию 1 место, штабелируемое)
+func (req cargoItemRequest) toCargoItem() ds.CargoItem {
+    item := ds.CargoItem{
+        Quantity:    req.Quantity,
+        Length:      req.Length,
+        Width:       req.Width,
+        Height:      req.Height,
+        Weight:      req.Weight,
+        Stackable:   true,
+        Fragile:     req.Fragile,
+        Description: req.Description,
+    }
+    if item.Quantity == 0 {
+        item.Quantity = 1
+    }
+    if req.Stackable != nil {
+        item.Stackable = *req.Stackable
+    }
+    return item
+}
+
+// AddCargoItem - добавление грузового места в заявку-черновик
+func (h *Handler) AddCargoItem(ctx *gin.Context) {
+    orderID, err := strconv.Atoi(ctx.Param("id"))
+    if err != nil {
+        fail(ctx, http.StatusBadRequest, "invalid logistic request id")
+        return
+    }
+    if _, _, ok := h.authorizeLogisticRequest(ctx, orderID, auth.PermRequestsWriteAny); !ok {
+        return
+    }
+
+    var req cargoItemRequest
+    if err := ctx.ShouldBindJSON(&req); err != nil {
+        fail(ctx, http.StatusBadRequest, "invalid request body")
+        return
+    }
+
+    item := req.toCargoItem()
+    if err := h.Repository.AddCargoItem(orderID, &item, h.actorID(ctx)); err != nil {
+        fail(ctx, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    ctx.JSON(http.StatusCreated, gin.H{"status": "ok", "cargo_item": item})
+}
+
+// UpdateCargoItem - изменение грузового места в заявке-черновике
+func (h *Handler) UpdateCargoItem(ctx *gin.Context) {
+    orderID, err := strconv.Atoi(ctx.Param("id"))
+    if err != nil {
+        fail(ctx, http.StatusBadRequest, "invalid logistic request id")
+        return
+    }
+    if _, _, ok := h.authorizeLogisticRequest(ctx, orderID, auth.PermRequestsWriteAny); !ok {
+        return
+    }
+    itemID, err := strconv.Atoi(ctx.Param("item_id"))
+    if err != nil {
+        fail(ctx, http.StatusBadRequest, "invalid cargo item id")
+        return
+    }
+
+    var req cargoItemRequest
+    if err := ctx.ShouldBindJSON(&req); err != nil {
+        fail(ctx, http.StatusBadRequest, "invalid request body")
+        return
+    }
+
+    item := req.toCargoItem()
+    if err := h.Repository.UpdateCargoItem(orderID, itemID, &item, h.actorID(ctx)); err != nil {
+        fail(ctx, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    ctx.JSON(http.StatusOK, gin.H{"status": "ok", "cargo_item": item})
+}
+
+// RemoveCargoItem - удаление грузового места из заявки-черновика
+func (h *Handler) RemoveCargoItem(ctx *gin.Context) {
+    orderID, err := strconv.Atoi(ctx.Param("id"))
+    if err != nil {
+        fail(ctx, http.StatusBadRequest, "invalid logistic request id")
+        return
+    }
+    if _, _, ok := h.authorizeLogisticRequest(ctx, orderID, auth.PermRequestsWriteAny); !ok {
+        return
+    }
+    itemID, err := strconv.Atoi(ctx.Param("item_id"))
+    if err != nil {
+        fail(ctx, http.StatusBadRequest, "invalid cargo item id")
+        return
+    }
+
+    if err := h.Repository.RemoveCargoItem(orderID, itemID, h.actorID(ctx)); err != nil {
+        fail(ctx, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    ctx.JSON(http.StatusOK, gin.H{"status": "ok", "message": "cargo item removed from logistic request"})
+}
+
 // ==================== М-М ЗАЯВКА-УСЛУГА ====================
 
 // AddServiceToLogisticRequest - добавление услуги в заявку
@@ -1314,7 +2450,11 @@ func (h *Handler) AddServiceToLogisticRequest(ctx *gin.Context) {
         return
     }
 
-    err := h.Repository.AddServiceToLogisticRequest(req.LogisticRequestID, req.TransportServiceID)
+    if _, _, ok := h.authorizeLogisticRequest(ctx, req.LogisticRequestID, auth.PermRequestsWriteAny); !ok {
+        return
+    }
+
+    err := h.Repository.AddServiceToLogisticRequest(req.LogisticRequestID, req.TransportServiceID, h.actorID(ctx))
     if err != nil {
         fail(ctx, http.StatusBadRequest, err.Error())
         return
@@ -1339,8 +2479,15 @@ func (h *Handler) RemoveServiceFromLogisticRequest(ctx *gin.Context) {
         fail(ctx, http.StatusBadRequest, "invalid service id")
         return
     }
+    if _, _, ok := h.authorizeLogisticRequest(ctx, orderID, auth.PermRequestsWriteAny); !ok {
+        return
+    }
 
-    err = h.Repository.RemoveServiceFromLogisticRequest(orderID, serviceID)
+    err = h.Repository.RemoveServiceFromLogisticRequest(orderID, serviceID, h.actorID(ctx))
+    if errors.Is(err, repository.ErrNotDraft) {
+        fail(ctx, http.StatusConflict, err.Error())
+        return
+    }
     if err != nil {
         fail(ctx, http.StatusBadRequest, err.Error())
         return
@@ -1365,6 +2512,9 @@ func (h *Handler) UpdateLogisticRequestService(ctx *gin.Context) {
         fail(ctx, http.StatusBadRequest, "invalid service id")
         return
     }
+    if _, _, ok := h.authorizeLogisticRequest(ctx, orderID, auth.PermRequestsWriteAny); !ok {
+        return
+    }
 
     var req struct {
         Quantity int    `json:"quantity" binding:"required,min=1"`
@@ -1377,7 +2527,11 @@ func (h *Handler) UpdateLogisticRequestService(ctx *gin.Context) {
         return
     }
 
-    err = h.Repository.UpdateLogisticRequestService(orderID, serviceID, req.Quantity, req.SortOrder, req.Comment)
+    err = h.Repository.UpdateLogisticRequestService(orderID, serviceID, req.Quantity, req.SortOrder, req.Comment, h.actorID(ctx))
+    if errors.Is(err, repository.ErrNotDraft) {
+        fail(ctx, http.StatusConflict, err.Error())
+        return
+    }
     if err != nil {
         fail(ctx, http.StatusBadRequest, err.Error())
         return
@@ -1385,3 +2539,46 @@ func (h *Handler) UpdateLogisticRequestService(ctx *gin.Context) {
 
     ctx.JSON(http.StatusOK, gin.H{"status": "ok", "message": "logistic request service updated"})
 }
+
+// GetJobRuns - история запусков фоновых задач (администратор)
+func (h *Handler) GetJobRuns(ctx *gin.Context) {
+    limit := 50
+    if s := ctx.Query("limit"); s != "" {
+        n, err := strconv.Atoi(s)
+        if err != nil || n < 1 || n > 500 {
+            fail(ctx, http.StatusBadRequest, "limit must be between 1 and 500")
+            return
+        }
+        limit = n
+    }
+
+    runs, err := h.Repository.GetJobRuns(ctx.Query("job"), limit)
+    if err != nil {
+        logrus.Error(err)
+        fail(ctx, http.StatusInternalServerError, "failed to get job runs")
+        return
+    }
+
+    ctx.JSON(http.StatusOK, gin.H{"status": "ok", "runs": runs})
+}
+
+// RestoreLogisticRequest - восстановление удалённой заявки в черновик (администратор)
+func (h *Handler) RestoreLogisticRequest(ctx *gin.Context) {
+    id, err := strconv.Atoi(ctx.Param("id"))
+    if err != nil {
+        fail(ctx, http.StatusBadRequest, "invalid logistic request id")
+        return
+    }
+    actor, ok := h.currentActor(ctx)
+    if !ok {
+        return
+    }
+
+    order, err := h.Repository.RestoreLogisticRequest(id, actor)
+    if err != nil {
+        failTransition(ctx, err)
+        return
+    }
+
+    ctx.JSON(http.StatusOK, gin.H{"status": "ok", "logistic_request": order})
+}
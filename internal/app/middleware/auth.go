@@ -11,17 +11,42 @@ import (
 
 // AuthMiddleware - middleware для проверки авторизации
 type AuthMiddleware struct {
-	jwtService *auth.JWTService
+	jwtService  *auth.JWTService
+	revocations auth.RevocationStore
 }
 
-// NewAuthMiddleware - создание нового middleware
-// Лаб7/требование: авторизация только по JWT, без Redis-сессий/blacklist.
-func NewAuthMiddleware(jwtService *auth.JWTService) *AuthMiddleware {
+// NewAuthMiddleware - создание нового middleware.
+// Кроме подписи и срока токена проверяется, не отозван ли он (revocations).
+func NewAuthMiddleware(jwtService *auth.JWTService, revocations auth.RevocationStore) *AuthMiddleware {
 	return &AuthMiddleware{
-		jwtService: jwtService,
+		jwtService:  jwtService,
+		revocations: revocations,
 	}
 }
 
+// rejectRevoked - ответ 401 на отозванный токен (503, если хранилище отзыва недоступно).
+// Возвращает true, если запрос прерван.
+func (am *AuthMiddleware) rejectRevoked(c *gin.Context, claims *auth.JWTClaims) bool {
+	revoked, err := auth.IsRevoked(am.revocations, claims)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":  "error",
+			"message": "Token revocation check unavailable",
+		})
+		c.Abort()
+		return true
+	}
+	if revoked {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status":  "error",
+			"message": "Token revoked",
+		})
+		c.Abort()
+		return true
+	}
+	return false
+}
+
 // RequireAuth - middleware для обязательной авторизации
 func (am *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -55,6 +80,10 @@ func (am *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 			return
 		}
 
+		if am.rejectRevoked(c, claims) {
+			return
+		}
+
 		// Сохраняем информацию о пользователе в контексте
 		c.Set("user_uuid", claims.UserUUID)
 		c.Set("user_role", claims.Role)
@@ -103,6 +132,34 @@ func (am *AuthMiddleware) RequireRole(allowedRoles ...string) gin.HandlerFunc {
 	}
 }
 
+// RequirePermission - middleware для проверки прав роли (после RequireAuth): нужны все перечисленные права
+func (am *AuthMiddleware) RequirePermission(permissions ...auth.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, exists := GetUserRole(c)
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"status":  "error",
+				"message": "User role not found",
+			})
+			c.Abort()
+			return
+		}
+
+		for _, permission := range permissions {
+			if !auth.HasPermission(role, permission) {
+				c.JSON(http.StatusForbidden, gin.H{
+					"status":  "error",
+					"message": "Insufficient permissions: " + string(permission) + " required",
+				})
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
 // RequireModerator - middleware для модераторов (Manager или Admin)
 func (am *AuthMiddleware) RequireModerator() gin.HandlerFunc {
 	return gin.HandlerFunc(func(c *gin.Context) {
@@ -137,6 +194,10 @@ func (am *AuthMiddleware) RequireModerator() gin.HandlerFunc {
 			return
 		}
 
+		if am.rejectRevoked(c, claims) {
+			return
+		}
+
 		// Проверяем роль
 		if claims.Role != ds.RoleManager && claims.Role != ds.RoleAdmin {
 			c.JSON(http.StatusForbidden, gin.H{
@@ -171,6 +232,10 @@ func (am *AuthMiddleware) OptionalAuth() gin.HandlerFunc {
 			c.Next()
 			return
 		}
+		if revoked, err := auth.IsRevoked(am.revocations, claims); err != nil || revoked {
+			c.Next()
+			return
+		}
 
 		// Сохраняем информацию о пользователе в контексте
 		c.Set("user_uuid", claims.UserUUID)
@@ -220,6 +285,25 @@ func GetUserRole(c *gin.Context) (string, bool) {
 	return role, ok
 }
 
+// GetTokenClaims - claims проверенного access токена запроса
+func GetTokenClaims(c *gin.Context) (*auth.JWTClaims, bool) {
+	value, exists := c.Get("token_claims")
+	if !exists {
+		return nil, false
+	}
+	claims, ok := value.(*auth.JWTClaims)
+	return claims, ok
+}
+
+// GetSessionID - сессия (семейство refresh токенов), к которой относится access токен запроса
+func GetSessionID(c *gin.Context) (string, bool) {
+	claims, ok := GetTokenClaims(c)
+	if !ok || claims.SessionID == "" {
+		return "", false
+	}
+	return claims.SessionID, true
+}
+
 // IsAuthenticated - проверка, авторизован ли пользователь
 func IsAuthenticated(c *gin.Context) bool {
 	_, exists := c.Get("user_uuid")
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// GuestSessionCookie - cookie с ID гостевой сессии (ключ черновика заявки гостя)
+const GuestSessionCookie = "guest_session"
+
+// GuestSession - middleware гостевой сессии: читает ID из cookie или выдаёт новый.
+// Cookie продлевается на каждом запросе, поэтому активный гость не теряет черновик.
+func GuestSession(ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessionID, ok := GuestSessionFromCookie(c)
+		if !ok {
+			sessionID = uuid.NewString()
+		}
+
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(GuestSessionCookie, sessionID, int(ttl.Seconds()), "/", "", c.Request.TLS != nil, true)
+		c.Set("guest_session_id", sessionID)
+
+		c.Next()
+	}
+}
+
+// GuestSessionFromCookie - ID гостевой сессии из cookie (без выдачи нового)
+func GuestSessionFromCookie(c *gin.Context) (string, bool) {
+	sessionID, err := c.Cookie(GuestSessionCookie)
+	if err != nil {
+		return "", false
+	}
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return "", false
+	}
+	return sessionID, true
+}
+
+// GetGuestSessionID - ID гостевой сессии из контекста
+func GetGuestSessionID(c *gin.Context) string {
+	sessionID, _ := c.Get("guest_session_id")
+	id, _ := sessionID.(string)
+	return id
+}
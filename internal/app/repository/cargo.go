@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"rip-go-app/internal/app/calculator"
+	"rip-go-app/internal/app/ds"
+)
+
+// ==================== ГРУЗОВЫЕ МЕСТА ====================
+
+// cargoPieceOf - грузовое место в виде, понятном калькулятору
+func cargoPieceOf(item ds.CargoItem) calculator.CargoPiece {
+	return calculator.CargoPiece{
+		Quantity: item.Quantity,
+		Length:   item.Length,
+		Width:    item.Width,
+		Height:   item.Height,
+		Weight:   item.Weight,
+	}
+}
+
+// cargoPiecesOf - места груза заявки; для заявок без мест — одно место из общих параметров
+func cargoPiecesOf(order ds.LogisticRequest) []calculator.CargoPiece {
+	if len(order.CargoItems) == 0 {
+		return []calculator.CargoPiece{
+			{Quantity: 1, Length: order.Length, Width: order.Width, Height: order.Height, Weight: order.Weight},
+		}
+	}
+	pieces := make([]calculator.CargoPiece, 0, len(order.CargoItems))
+	for _, item := range order.CargoItems {
+		pieces = append(pieces, cargoPieceOf(item))
+	}
+	return pieces
+}
+
+// validateCargoItem - проверка места: положительные значения и габариты каждого транспорта заявки
+func validateCargoItem(item ds.CargoItem, services []ds.LogisticRequestService) error {
+	if item.Quantity < 1 {
+		return fmt.Errorf("количество мест должно быть не меньше 1")
+	}
+	if item.Length <= 0 || item.Width <= 0 || item.Height <= 0 || item.Weight <= 0 {
+		return fmt.Errorf("габариты и вес места должны быть больше 0")
+	}
+	for _, s := range services {
+		if err := calculator.CheckCargoPiece(s.TransportService, cargoPieceOf(item)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recalcCargoAggregates - пересчет общего веса, объема и габаритов заявки по её местам
+func recalcCargoAggregates(tx *gorm.DB, orderID int) error {
+	var items []ds.CargoItem
+	if err := tx.Where("logistic_request_id = ?", orderID).Find(&items).Error; err != nil {
+		return err
+	}
+	pieces := make([]calculator.CargoPiece, 0, len(items))
+	for _, item := range items {
+		pieces = append(pieces, cargoPieceOf(item))
+	}
+	length, width, height := calculator.MaxPieceDimensions(pieces)
+	return tx.Model(&ds.LogisticRequest{}).Where("id = ?", orderID).Updates(map[string]interface{}{
+		"weight": calculator.TotalWeight(pieces),
+		"volume": calculator.TotalVolume(pieces),
+		"length": length,
+		"width":  width,
+		"height": height,
+	}).Error
+}
+
+// getDraftForCargo - заявка-черновик с услугами для изменения мест груза
+func (r *Repository) getDraftForCargo(orderID int) (ds.LogisticRequest, error) {
+	var order ds.LogisticRequest
+	err := r.db.Preload("Services.TransportService", withDeleted).
+		Where("id = ?", orderID).First(&order).Error
+	if err != nil {
+		return ds.LogisticRequest{}, fmt.Errorf("заявка не найдена")
+	}
+	if order.Status != ds.StatusDraft {
+		return ds.LogisticRequest{}, fmt.Errorf("места груза можно менять только в черновике")
+	}
+	return order, nil
+}
+
+// GetCargoItems - грузовые места заявки
+func (r *Repository) GetCargoItems(orderID int) ([]ds.CargoItem, error) {
+	var items []ds.CargoItem
+	err := r.db.Where("logistic_request_id = ?", orderID).Order("id").Find(&items).Error
+	return items, err
+}
+
+// AddCargoItem - добавление места в заявку-черновик
+func (r *Repository) AddCargoItem(orderID int, item *ds.CargoItem, actorID int) error {
+	order, err := r.getDraftForCargo(orderID)
+	if err != nil {
+		return err
+	}
+	if err := validateCargoItem(*item, order.Services); err != nil {
+		return err
+	}
+
+	item.ID = 0
+	item.LogisticRequestID = orderID
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(item).Error; err != nil {
+			return err
+		}
+		if err := recordEvent(tx, actorID, ds.LogisticRequestEvent{
+			LogisticRequestID: orderID,
+			Type:              ds.EventCargoItemAdded,
+			SubjectID:         &item.ID,
+			NewValue:          describeCargoItem(*item),
+		}); err != nil {
+			return err
+		}
+		return recalcCargoAggregates(tx, orderID)
+	})
+}
+
+// UpdateCargoItem - изменение места в заявке-черновике
+func (r *Repository) UpdateCargoItem(orderID, itemID int, item *ds.CargoItem, actorID int) error {
+	order, err := r.getDraftForCargo(orderID)
+	if err != nil {
+		return err
+	}
+
+	var existing ds.CargoItem
+	if err := r.db.Where("id = ? AND logistic_request_id = ?", itemID, orderID).First(&existing).Error; err != nil {
+		return fmt.Errorf("место не найдено в заявке")
+	}
+	if err := validateCargoItem(*item, order.Services); err != nil {
+		return err
+	}
+
+	item.ID = itemID
+	item.LogisticRequestID = orderID
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(item).Error; err != nil {
+			return err
+		}
+		if err := recordEvent(tx, actorID, ds.LogisticRequestEvent{
+			LogisticRequestID: orderID,
+			Type:              ds.EventCargoItemUpdated,
+			SubjectID:         &itemID,
+			OldValue:          describeCargoItem(existing),
+			NewValue:          describeCargoItem(*item),
+		}); err != nil {
+			return err
+		}
+		return recalcCargoAggregates(tx, orderID)
+	})
+}
+
+// RemoveCargoItem - удаление места из заявки-черновика
+func (r *Repository) RemoveCargoItem(orderID, itemID, actorID int) error {
+	if _, err := r.getDraftForCargo(orderID); err != nil {
+		return err
+	}
+
+	var existing ds.CargoItem
+	if err := r.db.Where("id = ? AND logistic_request_id = ?", itemID, orderID).First(&existing).Error; err != nil {
+		return fmt.Errorf("место не найдено в заявке")
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&existing).Error; err != nil {
+			return err
+		}
+		if err := recordEvent(tx, actorID, ds.LogisticRequestEvent{
+			LogisticRequestID: orderID,
+			Type:              ds.EventCargoItemRemoved,
+			SubjectID:         &itemID,
+			OldValue:          describeCargoItem(existing),
+		}); err != nil {
+			return err
+		}
+		return recalcCargoAggregates(tx, orderID)
+	})
+}
@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"rip-go-app/internal/app/calculator"
+	"rip-go-app/internal/app/ds"
+)
+
+// ==================== КАТАЛОГ УСЛУГ ====================
+
+// Полнотекстовый поиск по столбцу search_vector (см. cmd/migrate): русская морфология,
+// название весит больше описания. Короткий ввод без полного слова ищется по подстроке названия.
+const (
+	serviceQuerySQL = "websearch_to_tsquery('russian', ?)"
+	serviceRankSQL  = "ts_rank(search_vector, " + serviceQuerySQL + ")"
+
+	// Подсветка совпадений: название целиком, из описания — фрагменты вокруг совпадений
+	nameHighlightOptions = "HighlightAll=true, StartSel=<mark>, StopSel=</mark>"
+	snippetOptions       = "StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=20, MinWords=8, FragmentDelimiter=\" … \""
+)
+
+// matchServiceSearch - условие поиска услуг по запросу
+func matchServiceSearch(db *gorm.DB, search string) *gorm.DB {
+	return db.Where("search_vector @@ "+serviceQuerySQL+" OR LOWER(name) LIKE ?",
+		search, "%"+strings.ToLower(search)+"%")
+}
+
+// fitsCargo - услуги, ограничения которых принимают груз. Условие повторяет calculator.CheckCargoFit:
+// незаполненные габариты транспорта заменяются значениями тарифа по умолчанию.
+func fitsCargo(db *gorm.DB, pieces []calculator.CargoPiece) *gorm.DB {
+	length, width, height := calculator.MaxPieceDimensions(pieces)
+	defaults := calculator.DefaultTariff
+	return db.Where("max_weight >= ? AND max_volume >= ?", calculator.TotalWeight(pieces), calculator.TotalVolume(pieces)).
+		Where("(CASE WHEN max_length > 0 THEN max_length ELSE ? END) >= ?", defaults.MaxLength, length).
+		Where("(CASE WHEN max_width > 0 THEN max_width ELSE ? END) >= ?", defaults.MaxWidth, width).
+		Where("(CASE WHEN max_height > 0 THEN max_height ELSE ? END) >= ?", defaults.MaxHeight, height)
+}
+
+// TransportServiceFilter - фильтры каталога транспортных услуг
+type TransportServiceFilter struct {
+	Search        string // полнотекстовый поиск по названию и описанию
+	TransportType string // подстрока названия (вид транспорта)
+	MinPrice      *float64
+	MaxPrice      *float64
+	DateFrom      *time.Time // дата создания (от)
+	DateTo        *time.Time // дата создания (до)
+	// Диапазоны характеристик транспорта
+	MaxWeightFrom    *float64
+	MaxWeightTo      *float64
+	MaxVolumeFrom    *float64
+	MaxVolumeTo      *float64
+	DeliveryDaysFrom *int
+	DeliveryDaysTo   *int
+	// Только услуги, которые принимают груз по весу, объему и габаритам
+	Cargo          []calculator.CargoPiece
+	IncludeDeleted bool // вместе с удалёнными услугами (для администратора)
+}
+
+// TransportServiceHit - услуга в каталоге; при поиске — с релевантностью и подсвеченными совпадениями
+type TransportServiceHit struct {
+	ds.TransportService `gorm:"embedded"`
+	Rank                float64 `json:"rank,omitempty" gorm:"column:rank;->"`
+	NameHighlight       string  `json:"name_highlight,omitempty" gorm:"column:name_highlight;->"`
+	Snippet             string  `json:"snippet,omitempty" gorm:"column:snippet;->"`
+}
+
+// catalogSorting - поля сортировки каталога; по релевантности — только при поиске
+func catalogSorting(search bool) sortOptions[TransportServiceHit] {
+	opts := sortOptions[TransportServiceHit]{
+		columns: map[string]sortColumn[TransportServiceHit]{
+			"price":         {"price", func(s TransportServiceHit) any { return s.Price }},
+			"delivery_days": {"delivery_days", func(s TransportServiceHit) any { return s.DeliveryDays }},
+			"created_at":    {"created_at", func(s TransportServiceHit) any { return s.CreatedAt }},
+		},
+		defaultSort: "created_at",
+		id:          func(s TransportServiceHit) int { return s.ID },
+	}
+	if search {
+		opts.columns["relevance"] = sortColumn[TransportServiceHit]{"rank", func(s TransportServiceHit) any { return s.Rank }}
+		opts.defaultSort = "-relevance"
+	}
+	return opts
+}
+
+// catalogQuery - запрос услуг каталога по фильтрам (при поиске — с релевантностью и подсветкой)
+func (r *Repository) catalogQuery(filter TransportServiceFilter) *gorm.DB {
+	query := r.db.Model(&ds.TransportService{})
+	if filter.IncludeDeleted {
+		query = query.Unscoped()
+	}
+
+	search := strings.TrimSpace(filter.Search)
+	if search != "" {
+		query = matchServiceSearch(query, search).Select(
+			"transport_services.*, "+serviceRankSQL+" AS rank, "+
+				"ts_headline('russian', name, "+serviceQuerySQL+", ?) AS name_highlight, "+
+				"ts_headline('russian', description, "+serviceQuerySQL+", ?) AS snippet",
+			search, search, nameHighlightOptions, search, snippetOptions)
+	}
+	if filter.TransportType != "" {
+		query = query.Where("LOWER(name) LIKE ?", "%"+strings.ToLower(filter.TransportType)+"%")
+	}
+
+	query = whereRange(query, "price", filter.MinPrice, filter.MaxPrice)
+	query = whereRange(query, "created_at", filter.DateFrom, filter.DateTo)
+	query = whereRange(query, "max_weight", filter.MaxWeightFrom, filter.MaxWeightTo)
+	query = whereRange(query, "max_volume", filter.MaxVolumeFrom, filter.MaxVolumeTo)
+	query = whereRange(query, "delivery_days", filter.DeliveryDaysFrom, filter.DeliveryDaysTo)
+
+	if len(filter.Cargo) > 0 {
+		query = fitsCargo(query, filter.Cargo)
+	}
+	return query
+}
+
+// ListTransportServices - страница каталога услуг с фильтрами и сортировкой для API.
+// При поиске результаты по умолчанию упорядочены по релевантности и содержат подсветку совпадений.
+func (r *Repository) ListTransportServices(filter TransportServiceFilter, page PageRequest) (Page[TransportServiceHit], error) {
+	// Сортировка и курсор работают по столбцам подзапроса (в том числе rank).
+	// Мягкое удаление уже учтено во внутреннем запросе.
+	catalog := r.db.Unscoped().Table("(?) AS catalog", r.catalogQuery(filter))
+	return paginate(catalog, page, catalogSorting(strings.TrimSpace(filter.Search) != ""))
+}
+
+// RejectedService - услуга, не принявшая груз, и нарушенные ограничения
+type RejectedService struct {
+	ServiceID   int                          `json:"service_id"`
+	Name        string                       `json:"name"`
+	Constraints []calculator.CargoConstraint `json:"constraints"`
+}
+
+// ExplainCargoFit - услуги, которые подходят под остальные фильтры, но отсеяны по грузу, с причинами
+func (r *Repository) ExplainCargoFit(filter TransportServiceFilter) ([]RejectedService, error) {
+	pieces := filter.Cargo
+	filter.Cargo = nil
+
+	var services []ds.TransportService
+	if err := r.catalogQuery(filter).Order("id").Find(&services).Error; err != nil {
+		return nil, err
+	}
+	rejected := make([]RejectedService, 0)
+	for _, service := range services {
+		if failed := calculator.CheckCargoFit(service, pieces); len(failed) > 0 {
+			rejected = append(rejected, RejectedService{ServiceID: service.ID, Name: service.Name, Constraints: failed})
+		}
+	}
+	return rejected, nil
+}
+
+// whereRange - условие from <= column <= to (nil — граница не задана)
+func whereRange[T any](db *gorm.DB, column string, from, to *T) *gorm.DB {
+	if from != nil {
+		db = db.Where(column+" >= ?", *from)
+	}
+	if to != nil {
+		db = db.Where(column+" <= ?", *to)
+	}
+	return db
+}
@@ -0,0 +1,269 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"rip-go-app/internal/app/calculator"
+	"rip-go-app/internal/app/ds"
+)
+
+// ==================== СПРАВОЧНИК ГОРОДОВ ====================
+
+// NormalizeCityName - приведение названия города к виду для сравнения
+// (регистр, ё, дефисы и точки как пробелы, лишние пробелы)
+func NormalizeCityName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = strings.ReplaceAll(name, "ё", "е")
+	name = strings.NewReplacer("-", " ", ".", " ", ",", " ").Replace(name)
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// cyrillicToLatin - упрощённая транслитерация для сопоставления латинского ввода ("moskva")
+var cyrillicToLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ж': "zh", 'з': "z",
+	'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p",
+	'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch",
+	'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+}
+
+// TransliterateCityName - транслитерация нормализованного названия в латиницу
+func TransliterateCityName(normalized string) string {
+	var b strings.Builder
+	for _, ch := range normalized {
+		if lat, ok := cyrillicToLatin[ch]; ok {
+			b.WriteString(lat)
+		} else {
+			b.WriteRune(ch)
+		}
+	}
+	return b.String()
+}
+
+// Виды совпадения для подсказок городов
+const (
+	CityMatchName     = "name"
+	CityMatchAlias    = "alias"
+	CityMatchTranslit = "translit"
+)
+
+// CitySuggestion - подсказка города для автодополнения
+type CitySuggestion struct {
+	ds.City
+	Match string `json:"match"` // name, alias или translit
+	score int
+}
+
+// cityKey - нормализованное написание города, по которому выполняется сопоставление
+type cityKey struct {
+	value string
+	match string
+}
+
+// cityKeys - все варианты написания города: название, псевдонимы, транслитерация
+func cityKeys(city ds.City) []cityKey {
+	name := NormalizeCityName(city.Name)
+	keys := []cityKey{{name, CityMatchName}}
+	for _, a := range city.Aliases {
+		keys = append(keys, cityKey{NormalizeCityName(a.Alias), CityMatchAlias})
+	}
+	keys = append(keys, cityKey{TransliterateCityName(name), CityMatchTranslit})
+	return keys
+}
+
+// matchCityScore - оценка совпадения ключа с запросом (0 — нет совпадения).
+// Порядок: точное совпадение, префикс, префикс слова, подстрока; название важнее псевдонима.
+func matchCityScore(key cityKey, query string) int {
+	score := 0
+	switch {
+	case key.value == query:
+		score = 100
+	case strings.HasPrefix(key.value, query):
+		score = 80
+	case strings.Contains(" "+key.value, " "+query):
+		score = 60
+	case strings.Contains(key.value, query):
+		score = 40
+	default:
+		return 0
+	}
+	if key.match != CityMatchName {
+		score -= 5
+	}
+	return score
+}
+
+// SearchCities - ранжированные подсказки городов по введённой строке
+func (r *Repository) SearchCities(query string, limit int) ([]CitySuggestion, error) {
+	normalized := NormalizeCityName(query)
+	if normalized == "" {
+		return []CitySuggestion{}, nil
+	}
+
+	cities, _, err := r.cityIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := make([]CitySuggestion, 0)
+	for _, city := range cities {
+		best := CitySuggestion{City: city}
+		for _, key := range cityKeys(city) {
+			if score := matchCityScore(key, normalized); score > best.score {
+				best.score = score
+				best.Match = key.match
+			}
+		}
+		if best.score > 0 {
+			suggestions = append(suggestions, best)
+		}
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		if suggestions[i].score != suggestions[j].score {
+			return suggestions[i].score > suggestions[j].score
+		}
+		return suggestions[i].Name < suggestions[j].Name
+	})
+
+	if limit > 0 && len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions, nil
+}
+
+// GetCities - получение всех городов справочника
+func (r *Repository) GetCities() ([]ds.City, error) {
+	var cities []ds.City
+	err := r.db.Preload("Aliases").Order("name").Find(&cities).Error
+	return cities, err
+}
+
+// cityDirectoryTTL - как долго справочник городов в памяти считается актуальным.
+// Города и псевдонимы меняет только миграция (отдельный процесс), поэтому достаточно периодического перечитывания.
+const cityDirectoryTTL = 5 * time.Minute
+
+// cityDirectory - справочник городов в памяти с индексом всех написаний для FindCity
+type cityDirectory struct {
+	mu       sync.RWMutex
+	cities   []ds.City
+	index    map[string]int // нормализованное написание → индекс в cities
+	loadedAt time.Time
+}
+
+// newCityIndex - индекс написаний городов; одинаковое написание у нескольких городов ведет к первому по алфавиту
+func newCityIndex(cities []ds.City) map[string]int {
+	index := make(map[string]int)
+	for i, city := range cities {
+		for _, key := range cityKeys(city) {
+			if _, ok := index[key.value]; !ok {
+				index[key.value] = i
+			}
+		}
+	}
+	return index
+}
+
+// cityIndex - города справочника и индекс их написаний: из памяти, а при устаревании — из БД
+func (r *Repository) cityIndex() ([]ds.City, map[string]int, error) {
+	if r.cities == nil {
+		cities, err := r.GetCities()
+		return cities, newCityIndex(cities), err
+	}
+
+	d := r.cities
+	d.mu.RLock()
+	if d.index != nil && time.Since(d.loadedAt) < cityDirectoryTTL {
+		defer d.mu.RUnlock()
+		return d.cities, d.index, nil
+	}
+	d.mu.RUnlock()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.index != nil && time.Since(d.loadedAt) < cityDirectoryTTL {
+		return d.cities, d.index, nil
+	}
+	cities, err := r.GetCities()
+	if err != nil {
+		return nil, nil, err
+	}
+	d.cities, d.index, d.loadedAt = cities, newCityIndex(cities), time.Now()
+	return d.cities, d.index, nil
+}
+
+// GetCity - получение города по ID
+func (r *Repository) GetCity(id int) (ds.City, error) {
+	var city ds.City
+	err := r.db.Preload("Aliases").Where("id = ?", id).First(&city).Error
+	if err != nil {
+		return ds.City{}, fmt.Errorf("город не найден")
+	}
+	return city, nil
+}
+
+// FindCity - поиск города по точному написанию: названию, псевдониму ("спб") или транслитерации
+func (r *Repository) FindCity(name string) (ds.City, error) {
+	normalized := NormalizeCityName(name)
+	if normalized == "" {
+		return ds.City{}, fmt.Errorf("не указан город")
+	}
+
+	cities, index, err := r.cityIndex()
+	if err != nil {
+		return ds.City{}, err
+	}
+	if i, ok := index[normalized]; ok {
+		return cities[i], nil
+	}
+	return ds.City{}, fmt.Errorf("город не найден: %s", strings.TrimSpace(name))
+}
+
+// GetRouteDistance - явно заданное расстояние между городами (в любую сторону)
+func (r *Repository) GetRouteDistance(fromCityID, toCityID int) (float64, bool, error) {
+	var routes []ds.RouteDistance
+	err := r.db.Where("(from_city_id = ? AND to_city_id = ?) OR (from_city_id = ? AND to_city_id = ?)",
+		fromCityID, toCityID, toCityID, fromCityID).
+		Limit(1).Find(&routes).Error
+	if err != nil {
+		return 0, false, err
+	}
+	if len(routes) == 0 {
+		return 0, false, nil
+	}
+	return routes[0].DistanceKm, true, nil
+}
+
+// CityDistance - расстояние между городами справочника.
+// Если пары нет в таблице расстояний, оценивается по координатам с дорожным коэффициентом.
+func (r *Repository) CityDistance(from, to ds.City) (float64, error) {
+	if from.ID == to.ID {
+		return 0, nil
+	}
+
+	distance, found, err := r.GetRouteDistance(from.ID, to.ID)
+	if err != nil {
+		return 0, err
+	}
+	if found {
+		return distance, nil
+	}
+
+	return calculator.EstimateRoadDistance(from.Latitude, from.Longitude, to.Latitude, to.Longitude), nil
+}
+
+// Distance - расстояние между городами по названиям (реализует calculator.DistanceProvider)
+func (r *Repository) Distance(fromCity, toCity string) (float64, error) {
+	from, err := r.FindCity(fromCity)
+	if err != nil {
+		return 0, err
+	}
+	to, err := r.FindCity(toCity)
+	if err != nil {
+		return 0, err
+	}
+	return r.CityDistance(from, to)
+}
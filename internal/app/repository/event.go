@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"rip-go-app/internal/app/ds"
+)
+
+// ==================== ЖУРНАЛ ИЗМЕНЕНИЙ ЗАЯВОК ====================
+
+// recordEvent - запись события в журнал заявки (actorID = 0 — без пользователя)
+func recordEvent(tx *gorm.DB, actorID int, event ds.LogisticRequestEvent) error {
+	if actorID != 0 {
+		event.ActorID = &actorID
+	}
+	event.ID = 0
+	return tx.Create(&event).Error
+}
+
+// recordStatusChange - запись перехода статуса
+func recordStatusChange(tx *gorm.DB, actorID, orderID int, from, to, comment string) error {
+	return recordEvent(tx, actorID, ds.LogisticRequestEvent{
+		LogisticRequestID: orderID,
+		Type:              ds.EventStatusChanged,
+		Field:             "status",
+		OldValue:          from,
+		NewValue:          to,
+		Comment:           comment,
+	})
+}
+
+// formatFloat - число без лишних нулей для журнала
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// requestFieldValues - отслеживаемые поля заявки в порядке вывода в журнале
+func requestFieldValues(order ds.LogisticRequest) [][2]string {
+	return [][2]string{
+		{"from_city", order.FromCity},
+		{"to_city", order.ToCity},
+		{"weight", formatFloat(order.Weight)},
+		{"length", formatFloat(order.Length)},
+		{"width", formatFloat(order.Width)},
+		{"height", formatFloat(order.Height)},
+		{"volume", formatFloat(order.Volume)},
+		{"total_cost", formatFloat(order.TotalCost)},
+		{"total_days", strconv.Itoa(order.TotalDays)},
+	}
+}
+
+// recordFieldChanges - запись изменённых полей заявки (по одному событию на поле)
+func recordFieldChanges(tx *gorm.DB, actorID int, before, after ds.LogisticRequest) error {
+	old := requestFieldValues(before)
+	for i, field := range requestFieldValues(after) {
+		if old[i][1] == field[1] {
+			continue
+		}
+		err := recordEvent(tx, actorID, ds.LogisticRequestEvent{
+			LogisticRequestID: after.ID,
+			Type:              ds.EventFieldChanged,
+			Field:             field[0],
+			OldValue:          old[i][1],
+			NewValue:          field[1],
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// describeCargoItem - краткое описание места для журнала ("2 × 1.2×0.8×1 м, 150 кг")
+func describeCargoItem(item ds.CargoItem) string {
+	s := fmt.Sprintf("%d × %s×%s×%s м, %s кг", item.Quantity,
+		formatFloat(item.Length), formatFloat(item.Width), formatFloat(item.Height), formatFloat(item.Weight))
+	if item.Description != "" {
+		s += ", " + item.Description
+	}
+	return s
+}
+
+// describeRoute - маршрут заявки для журнала ("Москва → Новороссийск (1); Новороссийск → Сочи (5)")
+func describeRoute(legs []RouteLegInput) string {
+	parts := make([]string, 0, len(legs))
+	for _, leg := range legs {
+		parts = append(parts, fmt.Sprintf("%s → %s (%d)", leg.FromCity, leg.ToCity, leg.TransportServiceID))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// GetLogisticRequestEvents - журнал изменений заявки в хронологическом порядке
+func (r *Repository) GetLogisticRequestEvents(orderID int) ([]ds.LogisticRequestEvent, error) {
+	var events []ds.LogisticRequestEvent
+	err := r.db.Preload("Actor").Where("logistic_request_id = ?", orderID).
+		Order("created_at, id").Find(&events).Error
+	return events, err
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"rip-go-app/internal/app/ds"
+)
+
+// ==================== ГОСТЕВЫЕ ЧЕРНОВИКИ ====================
+
+// guestDrafts - черновики гостевых сессий (создатель — системный пользователь, задан session_id)
+func guestDrafts(db *gorm.DB) *gorm.DB {
+	return db.Where("session_id <> '' AND creator_id = ? AND status = ?",
+		ds.GetCreatorID(), ds.StatusDraft)
+}
+
+// deleteDrafts - окончательное удаление черновиков вместе со строками услуг и местами груза
+func deleteDrafts(tx *gorm.DB, ids []int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	tx = tx.Unscoped()
+	if err := tx.Where("logistic_request_id IN ?", ids).Delete(&ds.LogisticRequestService{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("logistic_request_id IN ?", ids).Delete(&ds.CargoItem{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("logistic_request_id IN ?", ids).Delete(&ds.LogisticRequestEvent{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("id IN ?", ids).Delete(&ds.LogisticRequest{}).Error
+}
+
+// CleanupGuestDrafts - удаление брошенных гостевых черновиков, неактивных дольше ttl.
+// Возвращает количество удалённых черновиков.
+func (r *Repository) CleanupGuestDrafts(ttl time.Duration) (int, error) {
+	var ids []int
+	err := guestDrafts(r.db.Model(&ds.LogisticRequest{})).
+		Where("updated_at < ?", time.Now().Add(-ttl)).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return 0, err
+	}
+	if err := r.db.Transaction(func(tx *gorm.DB) error { return deleteDrafts(tx, ids) }); err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+// MergeGuestDraft - перенос гостевого черновика в черновик пользователя при входе или регистрации.
+// Количество одинаковых услуг складывается, гостевой черновик удаляется. Возвращает ID черновика пользователя
+// (0 — переносить было нечего).
+func (r *Repository) MergeGuestDraft(sessionID string, userID int) (int, error) {
+	var guest ds.LogisticRequest
+	err := guestDrafts(r.db).Preload("Services").Preload("CargoItems").
+		Where("session_id = ?", sessionID).First(&guest).Error
+	if err != nil {
+		return 0, nil
+	}
+	if len(guest.Services) == 0 && len(guest.CargoItems) == 0 {
+		return 0, r.db.Transaction(func(tx *gorm.DB) error { return deleteDrafts(tx, []int{guest.ID}) })
+	}
+
+	draft, err := r.GetDraftLogisticRequest(userID)
+	if err != nil {
+		if draft, err = r.CreateDraftLogisticRequest(userID); err != nil {
+			return 0, err
+		}
+	}
+	if draft.ID == guest.ID {
+		return draft.ID, nil
+	}
+
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		for _, line := range guest.Services {
+			serviceID := line.TransportServiceID
+			existing, found := findRequestLine(tx, draft.ID, serviceID)
+			if found {
+				if existing.DeletedAt.Valid {
+					existing.DeletedAt = gorm.DeletedAt{}
+					existing.Quantity = 0
+				}
+				existing.Quantity += line.Quantity
+				if err := tx.Unscoped().Save(&existing).Error; err != nil {
+					return err
+				}
+			} else {
+				moved := ds.LogisticRequestService{
+					LogisticRequestID:  draft.ID,
+					TransportServiceID: serviceID,
+					Quantity:           line.Quantity,
+					SortOrder:          line.SortOrder,
+					Comment:            line.Comment,
+				}
+				if err := tx.Create(&moved).Error; err != nil {
+					return err
+				}
+			}
+			if err := recordEvent(tx, userID, ds.LogisticRequestEvent{
+				LogisticRequestID: draft.ID,
+				Type:              ds.EventServiceAdded,
+				SubjectID:         &serviceID,
+				NewValue:          strconv.Itoa(line.Quantity),
+				Comment:           "перенесено из гостевого черновика",
+			}); err != nil {
+				return err
+			}
+		}
+
+		if len(guest.CargoItems) > 0 {
+			if err := tx.Model(&ds.CargoItem{}).Where("logistic_request_id = ?", guest.ID).
+				Update("logistic_request_id", draft.ID).Error; err != nil {
+				return err
+			}
+			if err := recalcCargoAggregates(tx, draft.ID); err != nil {
+				return err
+			}
+		}
+
+		return deleteDrafts(tx, []int{guest.ID})
+	})
+	if err != nil {
+		return 0, err
+	}
+	return draft.ID, nil
+}
@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"rip-go-app/internal/app/ds"
+	"rip-go-app/internal/app/workflow"
+)
+
+// ==================== ФОНОВЫЕ ЗАДАЧИ ====================
+
+// AcquireJobLease - взятие аренды задачи на ttl (реализует scheduler.Store).
+// Аренда достаётся, если её нет, она истекла или уже принадлежит holder.
+func (r *Repository) AcquireJobLease(name, holder string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	res := r.db.Exec(`
+		INSERT INTO job_leases (name, holder, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT (name) DO UPDATE SET holder = EXCLUDED.holder, expires_at = EXCLUDED.expires_at
+		WHERE job_leases.expires_at < ? OR job_leases.holder = EXCLUDED.holder
+	`, name, holder, now.Add(ttl), now)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// RecordJobRun - запись запуска задачи в историю (реализует scheduler.Store)
+func (r *Repository) RecordJobRun(run *ds.JobRun) error {
+	return r.db.Create(run).Error
+}
+
+// GetJobRuns - история запусков задач, новые сначала (name = "" — все задачи)
+func (r *Repository) GetJobRuns(name string, limit int) ([]ds.JobRun, error) {
+	var runs []ds.JobRun
+	query := r.db.Order("started_at DESC, id DESC")
+	if name != "" {
+		query = query.Where("name = ?", name)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	err := query.Find(&runs).Error
+	return runs, err
+}
+
+// ExpireQuotes - отметка истёкших расчетов. Возвращает количество отмеченных.
+func (r *Repository) ExpireQuotes() (int, error) {
+	now := time.Now()
+	res := r.db.Model(&ds.Quote{}).Where("expired_at IS NULL AND valid_until < ?", now).Update("expired_at", now)
+	return int(res.RowsAffected), res.Error
+}
+
+// AutoRejectStaleRequests - отклонение сформированных заявок, которые не рассмотрели за maxAge.
+// Отклонение идёт через workflow от имени actor с комментарием в журнале.
+func (r *Repository) AutoRejectStaleRequests(maxAge time.Duration, actor workflow.Actor) (int, error) {
+	var ids []int
+	err := r.db.Model(&ds.LogisticRequest{}).
+		Where("status = ? AND formed_at < ?", ds.StatusFormed, time.Now().Add(-maxAge)).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return 0, err
+	}
+
+	comment := fmt.Sprintf("автоматически отклонена: не рассмотрена за %d дн.", int(maxAge.Hours()/24))
+	rejected := 0
+	for _, id := range ids {
+		if _, err := r.CompleteLogisticRequest(id, ds.StatusRejected, actor, false, comment); err != nil {
+			return rejected, fmt.Errorf("заявка %d: %w", id, err)
+		}
+		rejected++
+	}
+	return rejected, nil
+}
+
+// PurgeResult - количество окончательно удалённых записей
+type PurgeResult struct {
+	Requests int
+	Lines    int
+	Services int
+}
+
+// PurgeDeletedRecords - окончательное удаление записей, удалённых мягко раньше retention.
+// Услуги, на которые ещё ссылаются строки или плечи заявок, не удаляются.
+func (r *Repository) PurgeDeletedRecords(retention time.Duration) (PurgeResult, error) {
+	cutoff := time.Now().Add(-retention)
+	expired := func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff)
+	}
+
+	var orderIDs []int
+	if err := r.db.Model(&ds.LogisticRequest{}).Scopes(expired).Pluck("id", &orderIDs).Error; err != nil {
+		return PurgeResult{}, err
+	}
+
+	var serviceIDs []int
+	err := r.db.Model(&ds.TransportService{}).Scopes(expired).
+		Where("id NOT IN (?)", r.db.Unscoped().Model(&ds.LogisticRequestService{}).Select("transport_service_id")).
+		Where("id NOT IN (?)", r.db.Model(&ds.LogisticRequestLeg{}).Select("transport_service_id")).
+		Pluck("id", &serviceIDs).Error
+	if err != nil {
+		return PurgeResult{}, err
+	}
+
+	result := PurgeResult{Requests: len(orderIDs), Services: len(serviceIDs)}
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		if len(orderIDs) > 0 {
+			var quoteIDs []int
+			if err := tx.Model(&ds.Quote{}).Where("logistic_request_id IN ?", orderIDs).Pluck("id", &quoteIDs).Error; err != nil {
+				return err
+			}
+			if len(quoteIDs) > 0 {
+				if err := tx.Where("quote_id IN ?", quoteIDs).Delete(&ds.QuoteLine{}).Error; err != nil {
+					return err
+				}
+				if err := tx.Where("id IN ?", quoteIDs).Delete(&ds.Quote{}).Error; err != nil {
+					return err
+				}
+			}
+			for _, child := range []interface{}{&ds.LogisticRequestLeg{}, &ds.TrackingCheckpoint{}} {
+				if err := tx.Where("logistic_request_id IN ?", orderIDs).Delete(child).Error; err != nil {
+					return err
+				}
+			}
+			if err := deleteDrafts(tx, orderIDs); err != nil {
+				return err
+			}
+		}
+
+		// Удалённые строки услуг в действующих заявках
+		res := tx.Scopes(expired).Delete(&ds.LogisticRequestService{})
+		if res.Error != nil {
+			return res.Error
+		}
+		result.Lines = int(res.RowsAffected)
+
+		if len(serviceIDs) > 0 {
+			if err := tx.Where("transport_service_id IN ?", serviceIDs).Delete(&ds.ServiceTariff{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Where("id IN ?", serviceIDs).Delete(&ds.TransportService{}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return PurgeResult{}, err
+	}
+	return result, nil
+}
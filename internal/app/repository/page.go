@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ==================== ПОСТРАНИЧНАЯ ВЫБОРКА ====================
+
+// Размер страницы
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// ErrInvalidPage - неверные параметры страницы (сортировка, курсор, лимит); проверяется через errors.Is
+var ErrInvalidPage = errors.New("неверные параметры страницы")
+
+// PageRequest - параметры страницы: limit/offset или непрозрачный курсор следующей страницы.
+// Sort - поле сортировки, "-" в начале — по убыванию.
+type PageRequest struct {
+	Limit  int
+	Offset int
+	Cursor string
+	Sort   string
+}
+
+// PageInfo - сведения о странице для ответа API
+type PageInfo struct {
+	Total      int64  `json:"total"`
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	Sort       string `json:"sort"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// Page - страница записей
+type Page[T any] struct {
+	Items []T
+	PageInfo
+}
+
+// sortColumn - поле, по которому разрешена сортировка
+type sortColumn[T any] struct {
+	expr  string      // выражение ORDER BY
+	value func(T) any // значение поля у записи для курсора: float64, int или time.Time
+}
+
+// sortOptions - разрешённые поля сортировки списка
+type sortOptions[T any] struct {
+	columns     map[string]sortColumn[T]
+	defaultSort string
+	id          func(T) int // ID записи — второй ключ сортировки
+}
+
+// pageCursor - содержимое курсора: сортировка и ключ последней записи страницы
+type pageCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+// encodeCursor - курсор в виде непрозрачной строки
+func encodeCursor(c pageCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor - разбор курсора
+func decodeCursor(s string) (pageCursor, error) {
+	var c pageCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || json.Unmarshal(data, &c) != nil {
+		return pageCursor{}, fmt.Errorf("%w: некорректный курсор", ErrInvalidPage)
+	}
+	return c, nil
+}
+
+// formatSortValue - значение поля сортировки в курсоре
+func formatSortValue(v any) string {
+	switch v := v.(type) {
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// parseSortValue - значение поля сортировки из курсора того же типа, что и у записи
+func parseSortValue(s string, like any) (any, error) {
+	switch like.(type) {
+	case time.Time:
+		return time.Parse(time.RFC3339Nano, s)
+	case float64:
+		return strconv.ParseFloat(s, 64)
+	case int:
+		return strconv.Atoi(s)
+	default:
+		return s, nil
+	}
+}
+
+// paginate - выборка страницы: подсчет всех записей, сортировка по разрешённому полю (затем по ID)
+// и смещение либо продолжение с курсора. Предзагрузка связей выполняется только для записей страницы.
+func paginate[T any](query *gorm.DB, req PageRequest, opts sortOptions[T], preload ...func(*gorm.DB) *gorm.DB) (Page[T], error) {
+	sortBy := req.Sort
+	if sortBy == "" {
+		sortBy = opts.defaultSort
+	}
+	desc := strings.HasPrefix(sortBy, "-")
+	column, ok := opts.columns[strings.TrimPrefix(sortBy, "-")]
+	if !ok {
+		allowed := make([]string, 0, len(opts.columns))
+		for name := range opts.columns {
+			allowed = append(allowed, name)
+		}
+		sort.Strings(allowed)
+		return Page[T]{}, fmt.Errorf("%w: сортировка по %q недоступна (доступны: %s)",
+			ErrInvalidPage, sortBy, strings.Join(allowed, ", "))
+	}
+
+	limit := req.Limit
+	if limit == 0 {
+		limit = DefaultPageLimit
+	}
+	if limit < 0 || limit > MaxPageLimit {
+		return Page[T]{}, fmt.Errorf("%w: limit должен быть от 1 до %d", ErrInvalidPage, MaxPageLimit)
+	}
+	if req.Offset < 0 {
+		return Page[T]{}, fmt.Errorf("%w: offset не может быть отрицательным", ErrInvalidPage)
+	}
+
+	page := Page[T]{PageInfo: PageInfo{Limit: limit, Offset: req.Offset, Sort: sortBy}}
+	if err := query.Session(&gorm.Session{}).Count(&page.Total).Error; err != nil {
+		return Page[T]{}, err
+	}
+
+	dir, cmp := "ASC", ">"
+	if desc {
+		dir, cmp = "DESC", "<"
+	}
+	find := query.Session(&gorm.Session{}).Scopes(preload...).
+		Order(fmt.Sprintf("%s %s, id %s", column.expr, dir, dir))
+
+	if req.Cursor != "" {
+		cursor, err := decodeCursor(req.Cursor)
+		if err != nil {
+			return Page[T]{}, err
+		}
+		if cursor.Sort != sortBy {
+			return Page[T]{}, fmt.Errorf("%w: курсор выдан для другой сортировки", ErrInvalidPage)
+		}
+		var zero T
+		value, err := parseSortValue(cursor.Value, column.value(zero))
+		if err != nil {
+			return Page[T]{}, fmt.Errorf("%w: некорректный курсор", ErrInvalidPage)
+		}
+		find = find.Where(fmt.Sprintf("(%[1]s %[2]s ?) OR (%[1]s = ? AND id %[2]s ?)", column.expr, cmp),
+			value, value, cursor.ID)
+		page.Offset = 0
+	} else if req.Offset > 0 {
+		find = find.Offset(req.Offset)
+	}
+
+	// Лишняя запись показывает, есть ли следующая страница
+	if err := find.Limit(limit + 1).Find(&page.Items).Error; err != nil {
+		return Page[T]{}, err
+	}
+	if len(page.Items) > limit {
+		page.Items = page.Items[:limit]
+		last := page.Items[limit-1]
+		page.NextCursor = encodeCursor(pageCursor{
+			Sort:  sortBy,
+			Value: formatSortValue(column.value(last)),
+			ID:    opts.id(last),
+		})
+	}
+	if page.Items == nil {
+		page.Items = []T{}
+	}
+	return page, nil
+}
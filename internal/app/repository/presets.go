@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"fmt"
+
+	"gorm.io/gorm/clause"
+	"rip-go-app/internal/app/ds"
+)
+
+// ==================== НАБОРЫ ФИЛЬТРОВ ====================
+
+// GetFilterPresets - наборы фильтров пользователя
+func (r *Repository) GetFilterPresets(userID int) ([]ds.FilterPreset, error) {
+	var presets []ds.FilterPreset
+	err := r.db.Where("user_id = ?", userID).Order("name").Find(&presets).Error
+	return presets, err
+}
+
+// GetFilterPreset - набор фильтров пользователя по ID (чужие наборы не находятся)
+func (r *Repository) GetFilterPreset(userID, id int) (ds.FilterPreset, error) {
+	var preset ds.FilterPreset
+	if err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&preset).Error; err != nil {
+		return ds.FilterPreset{}, fmt.Errorf("набор фильтров не найден")
+	}
+	return preset, nil
+}
+
+// SaveFilterPreset - сохранение набора фильтров; набор с тем же именем заменяется
+func (r *Repository) SaveFilterPreset(preset *ds.FilterPreset) error {
+	if preset.Name == "" {
+		return fmt.Errorf("название набора не может быть пустым")
+	}
+	preset.ID = 0
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"filters", "updated_at"}),
+	}).Create(preset).Error
+}
+
+// DeleteFilterPreset - удаление набора фильтров пользователя
+func (r *Repository) DeleteFilterPreset(userID, id int) error {
+	res := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&ds.FilterPreset{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("набор фильтров не найден")
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+	"rip-go-app/internal/app/calculator"
+	"rip-go-app/internal/app/ds"
+)
+
+// ==================== ЗАФИКСИРОВАННЫЕ РАСЧЕТЫ ====================
+
+// QuoteValidity - срок, в течение которого цена расчета зафиксирована
+const QuoteValidity = 72 * time.Hour
+
+// requestPricing - результат расчета заявки по текущим тарифам
+type requestPricing struct {
+	Lines     []ds.QuoteLine
+	TotalCost float64
+	TotalDays int
+}
+
+// priceLogisticRequest - расчет стоимости и сроков заявки по тарифам, действовавшим в момент at.
+// Для мультимодальной заявки итог считается по сохранённому маршруту.
+// Услуга, которую нельзя рассчитать, — ошибка: расчет без неё занизил бы цену заявки.
+func (r *Repository) priceLogisticRequest(order ds.LogisticRequest, at time.Time) (requestPricing, error) {
+	calc := calculator.NewDeliveryCalculator(r)
+	pieces := cargoPiecesOf(order)
+
+	var pricing requestPricing
+	for _, orderService := range order.Services {
+		service := r.serviceAt(orderService.TransportService, at)
+		res := calc.CalculateCargoDelivery(service, order.FromCity, order.ToCity, pieces)
+		if !res.IsValid {
+			return requestPricing{}, fmt.Errorf("услуга %q: %s", orderService.TransportService.Name, res.ErrorMessage)
+		}
+		pricing.TotalCost += res.TotalCost
+		if res.DeliveryDays > pricing.TotalDays {
+			pricing.TotalDays = res.DeliveryDays
+		}
+		pricing.Lines = append(pricing.Lines, ds.QuoteLine{
+			TransportServiceID: orderService.TransportServiceID,
+			TariffVersion:      service.TariffVersion,
+			Cost:               res.TotalCost,
+			DeliveryDays:       res.DeliveryDays,
+			CostBreakdown:      res.CostBreakdown,
+			DaysBreakdown:      res.DaysBreakdown,
+		})
+	}
+
+	if len(order.Legs) > 0 {
+		res, err := r.CalculateRouteAt(routeInputsFromLegs(order.Legs), pieces, at)
+		if err != nil {
+			return requestPricing{}, err
+		}
+		if !res.IsValid {
+			return requestPricing{}, fmt.Errorf("%s", res.ErrorMessage)
+		}
+		pricing.TotalCost = res.TotalCost
+		pricing.TotalDays = res.DeliveryDays
+	}
+	return pricing, nil
+}
+
+// createQuote - сохранение расчета заявки с окном действия
+func createQuote(tx *gorm.DB, order ds.LogisticRequest, pricing requestPricing) (ds.Quote, error) {
+	quote := ds.Quote{
+		LogisticRequestID: order.ID,
+		FromCity:          order.FromCity,
+		ToCity:            order.ToCity,
+		Weight:            order.Weight,
+		Volume:            order.Volume,
+		Length:            order.Length,
+		Width:             order.Width,
+		Height:            order.Height,
+		TotalCost:         pricing.TotalCost,
+		TotalDays:         pricing.TotalDays,
+		ValidUntil:        time.Now().Add(QuoteValidity),
+		Lines:             pricing.Lines,
+	}
+	if err := tx.Create(&quote).Error; err != nil {
+		return ds.Quote{}, err
+	}
+	return quote, nil
+}
+
+// GetQuote - получение зафиксированного расчета по ID
+func (r *Repository) GetQuote(id int) (ds.Quote, error) {
+	var quote ds.Quote
+	err := r.db.Preload("Lines").Where("id = ?", id).First(&quote).Error
+	if err != nil {
+		return ds.Quote{}, fmt.Errorf("расчет не найден")
+	}
+	return quote, nil
+}
+
+// CompletionPricing - цена, по которой завершена заявка, относительно зафиксированного расчета
+type CompletionPricing struct {
+	QuoteID      *int    `json:"quote_id,omitempty"`
+	PriceLocked  bool    `json:"price_locked"`
+	QuoteExpired bool    `json:"quote_expired"`
+	QuotedCost   float64 `json:"quoted_cost"`
+	QuotedDays   int     `json:"quoted_days"`
+	CurrentCost  float64 `json:"current_cost"`
+	CurrentDays  int     `json:"current_days"`
+	CostDelta    float64 `json:"cost_delta"`
+	DaysDelta    int     `json:"days_delta"`
+}
+
+// QuoteExpiredError - расчет истёк и цена изменилась; модератор должен подтвердить новую цену
+type QuoteExpiredError struct {
+	Pricing CompletionPricing
+}
+
+func (e *QuoteExpiredError) Error() string {
+	return fmt.Sprintf("срок действия расчета истёк, стоимость изменилась на %.2f руб", e.Pricing.CostDelta)
+}
+
+// applyQuoteLines - перенос зафиксированных строк расчета на услуги заявки
+func applyQuoteLines(services []ds.LogisticRequestService, lines []ds.QuoteLine) {
+	byService := make(map[int]ds.QuoteLine, len(lines))
+	for _, line := range lines {
+		byService[line.TransportServiceID] = line
+	}
+	for i := range services {
+		line, ok := byService[services[i].TransportServiceID]
+		if !ok {
+			continue
+		}
+		services[i].Cost = line.Cost
+		services[i].DeliveryDays = line.DeliveryDays
+		services[i].CostBreakdown = line.CostBreakdown
+		services[i].DaysBreakdown = line.DaysBreakdown
+	}
+}
+
+// resolveCompletionPricing - выбор цены завершения: действующий расчет фиксирует цену,
+// истёкший — заменяется расчетом по текущим тарифам, если модератор подтвердил разницу.
+// Заявки без расчета считаются по тарифам на дату формирования.
+func (r *Repository) resolveCompletionPricing(order ds.LogisticRequest, acceptReprice bool) (requestPricing, CompletionPricing, error) {
+	pricedAt := time.Now()
+	if order.QuoteID == nil && order.FormedAt != nil {
+		pricedAt = *order.FormedAt
+	}
+	current, err := r.priceLogisticRequest(order, pricedAt)
+	if err != nil {
+		return requestPricing{}, CompletionPricing{}, err
+	}
+	summary := CompletionPricing{
+		QuoteID:     order.QuoteID,
+		CurrentCost: current.TotalCost,
+		CurrentDays: current.TotalDays,
+	}
+	if order.QuoteID == nil {
+		return current, summary, nil
+	}
+
+	quote, err := r.GetQuote(*order.QuoteID)
+	if err != nil {
+		return requestPricing{}, CompletionPricing{}, err
+	}
+	summary.QuotedCost = quote.TotalCost
+	summary.QuotedDays = quote.TotalDays
+	summary.CostDelta = math.Round((current.TotalCost-quote.TotalCost)*100) / 100
+	summary.DaysDelta = current.TotalDays - quote.TotalDays
+
+	if !quote.IsExpired(time.Now()) {
+		summary.PriceLocked = true
+		return requestPricing{Lines: quote.Lines, TotalCost: quote.TotalCost, TotalDays: quote.TotalDays}, summary, nil
+	}
+
+	summary.QuoteExpired = true
+	if (summary.CostDelta != 0 || summary.DaysDelta != 0) && !acceptReprice {
+		return requestPricing{}, summary, &QuoteExpiredError{Pricing: summary}
+	}
+	return current, summary, nil
+}
@@ -2,20 +2,24 @@ package repository
 
 import (
     "database/sql"
+    "errors"
     "fmt"
-    "strings"
+    "strconv"
     "time"
 
     "github.com/google/uuid"
     "github.com/sirupsen/logrus"
     "gorm.io/driver/postgres"
     "gorm.io/gorm"
+    "gorm.io/gorm/clause"
     "rip-go-app/internal/app/ds"
     "rip-go-app/internal/app/calculator"
+    "rip-go-app/internal/app/workflow"
 )
 
 type Repository struct {
-	db *gorm.DB
+	db     *gorm.DB
+	cities *cityDirectory // справочник городов в памяти (nil — без кеша)
 }
 
 func New(dsn string) (*Repository, error) {
@@ -26,61 +30,25 @@ func New(dsn string) (*Repository, error) {
 
 	// Возвращаем объект Repository с подключенной базой данных
 	return &Repository{
-		db: db,
+		db:     db,
+		cities: &cityDirectory{},
 	}, nil
 }
 
-// GetTransportServices - получение всех транспортных услуг с возможностью фильтрации (исключая удалённые)
-func (r *Repository) GetTransportServices(search string) ([]ds.TransportService, error) {
+// GetTransportServices - получение всех транспортных услуг с возможностью фильтрации (исключая удалённые).
+// cargo - только услуги, которые принимают груз (nil — без проверки)
+func (r *Repository) GetTransportServices(search string, cargo []calculator.CargoPiece) ([]ds.TransportService, error) {
 	var services []ds.TransportService
 	
-	query := r.db.Where("deleted_at IS NULL")
-	
-	if search != "" {
-		searchLower := strings.ToLower(search)
-		query = query.Where("LOWER(name) LIKE ? OR LOWER(description) LIKE ?", 
-			"%"+searchLower+"%", "%"+searchLower+"%")
+	query := r.db
+	if len(cargo) > 0 {
+		query = fitsCargo(query, cargo)
 	}
 	
-	err := query.Find(&services).Error
-	if err != nil {
-		return nil, err
-	}
-
-	return services, nil
-}
-
-// GetTransportServicesWithFilters - получение транспортных услуг с расширенными фильтрами для API
-func (r *Repository) GetTransportServicesWithFilters(search string, minPrice, maxPrice *float64, dateFrom, dateTo *time.Time) ([]ds.TransportService, error) {
-	var services []ds.TransportService
-	
-	query := r.db.Where("deleted_at IS NULL")
-	
-	// Поиск по названию и описанию
 	if search != "" {
-		searchLower := strings.ToLower(search)
-		query = query.Where("LOWER(name) LIKE ? OR LOWER(description) LIKE ?", 
-			"%"+searchLower+"%", "%"+searchLower+"%")
-	}
-	
-	// Фильтр по минимальной цене
-	if minPrice != nil {
-		query = query.Where("price >= ?", *minPrice)
-	}
-	
-	// Фильтр по максимальной цене
-	if maxPrice != nil {
-		query = query.Where("price <= ?", *maxPrice)
-	}
-	
-	// Фильтр по дате создания (от)
-	if dateFrom != nil {
-		query = query.Where("created_at >= ?", *dateFrom)
-	}
-	
-	// Фильтр по дате создания (до)
-	if dateTo != nil {
-		query = query.Where("created_at <= ?", *dateTo)
+		query = matchServiceSearch(query, search).Order(clause.OrderBy{Expression: clause.Expr{
+			SQL: serviceRankSQL + " DESC", Vars: []interface{}{search},
+		}})
 	}
 	
 	err := query.Find(&services).Error
@@ -120,15 +88,40 @@ func (r *Repository) GetTransportServiceByDeliveryType(deliveryType string) (ds.
 }
 
 // CRUD для TransportService
+// CreateTransportService - создание услуги с первой версией тарифа
 func (r *Repository) CreateTransportService(s *ds.TransportService) error {
-    return r.db.Create(s).Error
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        s.TariffVersion = 1
+        if err := tx.Create(s).Error; err != nil {
+            return err
+        }
+        tariff := ds.SnapshotTariff(*s)
+        tariff.ValidFrom = s.CreatedAt
+        return insertServiceTariff(tx, &tariff)
+    })
 }
 
+// UpdateTransportService - сохранение услуги; изменение закрывает текущую версию тарифа и открывает новую
 func (r *Repository) UpdateTransportService(s *ds.TransportService) error {
-    return r.db.Save(s).Error
+    if _, err := r.GetTransportService(s.ID); err != nil {
+        return err
+    }
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        tariff := ds.SnapshotTariff(*s)
+        tariff.ValidFrom = time.Now()
+        if err := insertServiceTariff(tx, &tariff); err != nil {
+            return err
+        }
+        s.TariffVersion = tariff.Version
+        return tx.Omit("CreatedAt").Save(s).Error
+    })
 }
 
+// DeleteTransportService - мягкое удаление услуги (заявки с ней сохраняются, новые добавить нельзя)
 func (r *Repository) DeleteTransportService(id int) error {
+    if _, err := r.GetTransportService(id); err != nil {
+        return err
+    }
     return r.db.Delete(&ds.TransportService{}, id).Error
 }
 
@@ -152,18 +145,59 @@ func (r *Repository) CreateCargoLogisticRequest(items []CargoLogisticRequestItem
 }
 
 func (r *Repository) createCargoLogisticRequestTx(items []CargoLogisticRequestItem, creatorID int) (int, error) {
-    calc := calculator.NewDeliveryCalculator()
+    calc := calculator.NewDeliveryCalculator(r)
 
-    returnID := 0
-    err := r.db.Transaction(func(tx *gorm.DB) error {
-        // Используем параметры первого как общие
-        first := items[0]
+    // Маршрут у заявки один: города каждой позиции приводим к справочнику и сверяем с первой
+    fromCity, err := r.FindCity(items[0].FromCity)
+    if err != nil {
+        return 0, err
+    }
+    toCity, err := r.FindCity(items[0].ToCity)
+    if err != nil {
+        return 0, err
+    }
+    for _, it := range items[1:] {
+        from, err := r.FindCity(it.FromCity)
+        if err != nil {
+            return 0, err
+        }
+        to, err := r.FindCity(it.ToCity)
+        if err != nil {
+            return 0, err
+        }
+        if from.ID != fromCity.ID || to.ID != toCity.ID {
+            return 0, fmt.Errorf("все позиции заявки должны иметь один маршрут")
+        }
+    }
+
+    // Позиции повторяют параметры одного и того же груза: место создаётся один раз на каждый
+    // различающийся набор габаритов, а не на каждую строку услуги
+    var pieces []ds.CargoItem
+    seen := make(map[[4]float64]bool)
+    for _, it := range items {
+        key := [4]float64{it.Length, it.Width, it.Height, it.Weight}
+        if seen[key] {
+            continue
+        }
+        seen[key] = true
+        pieces = append(pieces, ds.CargoItem{
+            Quantity:  1,
+            Length:    it.Length,
+            Width:     it.Width,
+            Height:    it.Height,
+            Weight:    it.Weight,
+            Stackable: true,
+        })
+    }
 
+    returnID := 0
+    err = r.db.Transaction(func(tx *gorm.DB) error {
         order := ds.LogisticRequest{
-            SessionID: "guest",
             IsDraft:   true,
-            FromCity:  first.FromCity,
-            ToCity:    first.ToCity,
+            FromCity:  fromCity.Name,
+            ToCity:    toCity.Name,
+            FromCityID: &fromCity.ID,
+            ToCityID:   &toCity.ID,
             Weight:    0,
             Length:    0,
             Width:     0,
@@ -177,20 +211,23 @@ func (r *Repository) createCargoLogisticRequestTx(items []CargoLogisticRequestIt
             return err
         }
 
+        for i := range pieces {
+            pieces[i].LogisticRequestID = order.ID
+            if err := tx.Create(&pieces[i]).Error; err != nil {
+                return err
+            }
+        }
+
         // агрегаты
         maxDays := 0
         totalCost := 0.0
-        totalWeight := 0.0
-        totalLength := 0.0
-        totalWidth := 0.0
-        totalHeight := 0.0
 
         for _, it := range items {
             svc, err := r.GetTransportService(it.TransportServiceID)
             if err != nil {
                 return fmt.Errorf("service %d not found", it.TransportServiceID)
             }
-            res := calc.CalculateDelivery(svc, it.FromCity, it.ToCity, it.Length, it.Width, it.Height, it.Weight)
+            res := calc.CalculateDelivery(svc, fromCity.Name, toCity.Name, it.Length, it.Width, it.Height, it.Weight)
             if !res.IsValid {
                 return fmt.Errorf("%s", res.ErrorMessage)
             }
@@ -203,24 +240,21 @@ func (r *Repository) createCargoLogisticRequestTx(items []CargoLogisticRequestIt
 
             if res.DeliveryDays > maxDays { maxDays = res.DeliveryDays }
             totalCost += res.TotalCost
-            totalWeight += it.Weight
-            totalLength += it.Length
-            totalWidth += it.Width
-            totalHeight += it.Height
         }
 
         // итоговые поля заказа
         order.TotalDays = maxDays
         order.TotalCost = totalCost
-        order.Weight = totalWeight
-        order.Length = totalLength
-        order.Width = totalWidth
-        order.Height = totalHeight
 
         if err := tx.Save(&order).Error; err != nil {
             return err
         }
 
+        // вес и объем — сумма по местам, габариты — наибольшие среди мест
+        if err := recalcCargoAggregates(tx, order.ID); err != nil {
+            return err
+        }
+
         returnID = order.ID
         return nil
     })
@@ -272,41 +306,115 @@ func (r *Repository) GetUserByUUID(userUUID string) (ds.User, error) {
     return user, nil
 }
 
-// UpdateUser - обновление пользователя
+// UpdateUser - обновление профиля пользователя.
+// Роль, блокировка и поколение токенов меняются только отдельными методами.
 func (r *Repository) UpdateUser(user *ds.User) error {
-    return r.db.Save(user).Error
+    return r.db.Omit("role", "disabled_at", "password_reset_required", "token_generation").Save(user).Error
 }
 
 // ==================== ЗАЯВКИ ====================
 
-// GetLogisticRequests - получение списка заявок с фильтрацией (исключая удалённые и черновики)
-func (r *Repository) GetLogisticRequests(status string, dateFrom, dateTo *time.Time) ([]ds.LogisticRequest, error) {
-    var orders []ds.LogisticRequest
-    
-    query := r.db.Preload("Creator").Preload("Moderator").
-        Where("deleted_at IS NULL AND status != ?", ds.StatusDraft)
+// LogisticRequestFilter - фильтры списка заявок (черновики в список не попадают)
+type LogisticRequestFilter struct {
+    Status         string
+    DateFrom       *time.Time // дата формирования (от)
+    DateTo         *time.Time // дата формирования (до)
+    CreatorID      int        // только заявки создателя (0 — все)
+    Creator        string     // создатель: часть логина или имени
+    Moderator      string     // модератор: часть логина или имени
+    FromCity       string
+    ToCity         string
+    ServiceID      int        // в заявке есть услуга (0 — любая)
+    MinCost        *float64
+    MaxCost        *float64
+    MinDays        *int
+    MaxDays        *int
+    Comment        string     // текст в комментариях к услугам заявки и к её изменениям
+    IncludeDeleted bool       // вместе с удалёнными (для администратора)
+}
+
+// matchUser - условие по пользователю из столбца column: часть логина или имени без учёта регистра
+func matchUser(db *gorm.DB, column, text string) *gorm.DB {
+    return db.Where(column+" IN (SELECT id FROM users WHERE login ILIKE ? OR name ILIKE ?)",
+        "%"+text+"%", "%"+text+"%")
+}
+
+// logisticRequestSorting - поля сортировки списка заявок.
+// У удалённых черновиков нет даты формирования, для них используется дата создания.
+var logisticRequestSorting = sortOptions[ds.LogisticRequest]{
+    columns: map[string]sortColumn[ds.LogisticRequest]{
+        "created_at": {"created_at", func(o ds.LogisticRequest) any { return o.CreatedAt }},
+        "formed_at": {"COALESCE(formed_at, created_at)", func(o ds.LogisticRequest) any {
+            if o.FormedAt != nil {
+                return *o.FormedAt
+            }
+            return o.CreatedAt
+        }},
+        "total_cost": {"total_cost", func(o ds.LogisticRequest) any { return o.TotalCost }},
+    },
+    defaultSort: "-created_at",
+    id:          func(o ds.LogisticRequest) int { return o.ID },
+}
+
+// ListLogisticRequests - страница заявок с фильтрами и сортировкой
+func (r *Repository) ListLogisticRequests(filter LogisticRequestFilter, page PageRequest) (Page[ds.LogisticRequest], error) {
+    query := r.db.Model(&ds.LogisticRequest{}).Where("status != ?", ds.StatusDraft)
+    if filter.IncludeDeleted {
+        query = query.Unscoped()
+    }
     
-    if status != "" {
-        query = query.Where("status = ?", status)
+    if filter.Status != "" {
+        query = query.Where("status = ?", filter.Status)
+    }
+    if filter.CreatorID != 0 {
+        query = query.Where("creator_id = ?", filter.CreatorID)
+    }
+    if filter.Creator != "" {
+        query = matchUser(query, "creator_id", filter.Creator)
+    }
+    if filter.Moderator != "" {
+        query = matchUser(query, "moderator_id", filter.Moderator)
+    }
+    if filter.FromCity != "" {
+        query = query.Where("LOWER(from_city) = LOWER(?)", filter.FromCity)
+    }
+    if filter.ToCity != "" {
+        query = query.Where("LOWER(to_city) = LOWER(?)", filter.ToCity)
+    }
+    if filter.ServiceID != 0 {
+        query = query.Where(`EXISTS (SELECT 1 FROM logistic_request_services s
+            WHERE s.logistic_request_id = logistic_requests.id AND s.transport_service_id = ? AND s.deleted_at IS NULL)`,
+            filter.ServiceID)
+    }
+    query = whereRange(query, "total_cost", filter.MinCost, filter.MaxCost)
+    query = whereRange(query, "total_days", filter.MinDays, filter.MaxDays)
+    if filter.Comment != "" {
+        text := "%" + filter.Comment + "%"
+        query = query.Where(`(EXISTS (SELECT 1 FROM logistic_request_services s
+            WHERE s.logistic_request_id = logistic_requests.id AND s.deleted_at IS NULL AND s.comment ILIKE ?)
+            OR EXISTS (SELECT 1 FROM logistic_request_events e
+            WHERE e.logistic_request_id = logistic_requests.id AND e.comment ILIKE ?))`, text, text)
     }
     
-    if dateFrom != nil {
-        query = query.Where("formed_at >= ?", *dateFrom)
+    if filter.DateFrom != nil {
+        query = query.Where("formed_at >= ?", *filter.DateFrom)
     }
     
-    if dateTo != nil {
-        query = query.Where("formed_at <= ?", *dateTo)
+    if filter.DateTo != nil {
+        query = query.Where("formed_at <= ?", *filter.DateTo)
     }
     
-    err := query.Order("created_at DESC").Find(&orders).Error
-    return orders, err
+    return paginate(query, page, logisticRequestSorting, func(db *gorm.DB) *gorm.DB {
+        return db.Preload("Creator").Preload("Moderator")
+    })
 }
 
 // GetLogisticRequest - получение заявки по ID с услугами
 func (r *Repository) GetLogisticRequest(id int) (ds.LogisticRequest, error) {
     var order ds.LogisticRequest
-    err := r.db.Preload("Services.TransportService").Preload("Creator").Preload("Moderator").
-        Where("id = ? AND deleted_at IS NULL", id).First(&order).Error
+    err := r.db.Preload("Services.TransportService", withDeleted).Preload("Creator").Preload("Moderator").
+        Preload("Legs", orderLegsBySequence).Preload("Legs.TransportService", withDeleted).Preload("CargoItems").
+        Where("id = ?", id).First(&order).Error
     if err != nil {
         return ds.LogisticRequest{}, fmt.Errorf("заявка не найдена")
     }
@@ -316,8 +424,8 @@ func (r *Repository) GetLogisticRequest(id int) (ds.LogisticRequest, error) {
 // GetDraftLogisticRequest - получение черновика заявки пользователя
 func (r *Repository) GetDraftLogisticRequest(creatorID int) (ds.LogisticRequest, error) {
     var order ds.LogisticRequest
-    err := r.db.Preload("Services.TransportService").
-        Where("creator_id = ? AND status = ? AND deleted_at IS NULL", creatorID, ds.StatusDraft).
+    err := r.db.Preload("Services.TransportService", withDeleted).
+        Where("creator_id = ? AND status = ?", creatorID, ds.StatusDraft).
         First(&order).Error
     if err != nil {
         return ds.LogisticRequest{}, fmt.Errorf("черновик не найден")
@@ -336,104 +444,188 @@ func (r *Repository) CreateDraftLogisticRequest(creatorID int) (ds.LogisticReque
     return order, err
 }
 
-// UpdateLogisticRequest - обновление заявки
-func (r *Repository) UpdateLogisticRequest(order *ds.LogisticRequest) error {
-    return r.db.Save(order).Error
+// UpdateLogisticRequest - обновление заявки с записью изменённых полей в журнал
+func (r *Repository) UpdateLogisticRequest(order *ds.LogisticRequest, actorID int) error {
+    var before ds.LogisticRequest
+    if err := r.db.Where("id = ?", order.ID).First(&before).Error; err != nil {
+        return fmt.Errorf("заявка не найдена")
+    }
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Omit("Services", "CargoItems", "Legs").Save(order).Error; err != nil {
+            return err
+        }
+        return recordFieldChanges(tx, actorID, before, *order)
+    })
 }
 
-// FormLogisticRequest - формирование заявки создателем (проверка обязательных полей)
-func (r *Repository) FormLogisticRequest(orderID int, fromCity, toCity string, weight, length, width, height float64) error {
+// FormLogisticRequest - формирование заявки создателем (проверка обязательных полей).
+// Если в заявке есть грузовые места, параметры груза берутся из них, а переданные игнорируются.
+func (r *Repository) FormLogisticRequest(orderID int, actor workflow.Actor, fromCity, toCity string, weight, length, width, height float64) (ds.Quote, error) {
     var order ds.LogisticRequest
-    err := r.db.Preload("Services").Where("id = ?", orderID).First(&order).Error
+    err := r.db.Preload("Services.TransportService", withDeleted).Preload("Legs", orderLegsBySequence).Preload("CargoItems").
+        Where("id = ?", orderID).First(&order).Error
     if err != nil {
-        return fmt.Errorf("заявка не найдена")
+        return ds.Quote{}, fmt.Errorf("заявка не найдена")
     }
     
-    // Проверяем, что заявка в статусе draft
-    if order.Status != ds.StatusDraft {
-        return fmt.Errorf("можно формировать только черновики")
+    // Формировать можно только свой черновик
+    if err := workflow.Check(order, ds.StatusFormed, actor); err != nil {
+        return ds.Quote{}, err
     }
     
     // Проверяем обязательные поля
-    if fromCity == "" || toCity == "" || weight <= 0 || length <= 0 || width <= 0 || height <= 0 {
-        return fmt.Errorf("не заполнены обязательные поля: города и параметры груза")
+    hasCargoItems := len(order.CargoItems) > 0
+    if fromCity == "" || toCity == "" || (!hasCargoItems && (weight <= 0 || length <= 0 || width <= 0 || height <= 0)) {
+        return ds.Quote{}, fmt.Errorf("не заполнены обязательные поля: города и параметры груза")
     }
     
     if len(order.Services) == 0 {
-        return fmt.Errorf("в заявке нет услуг")
+        return ds.Quote{}, fmt.Errorf("в заявке нет услуг")
+    }
+    
+    // Каждое место должно помещаться в каждый транспорт заявки
+    for i, item := range order.CargoItems {
+        if err := validateCargoItem(item, order.Services); err != nil {
+            return ds.Quote{}, fmt.Errorf("место %d: %s", i+1, err.Error())
+        }
+    }
+    
+    // Приводим города к справочнику (неизвестный город — ошибка)
+    from, err := r.FindCity(fromCity)
+    if err != nil {
+        return ds.Quote{}, err
+    }
+    to, err := r.FindCity(toCity)
+    if err != nil {
+        return ds.Quote{}, err
     }
     
     // Обновляем заявку
+    before := order
     now := time.Now()
-    order.FromCity = fromCity
-    order.ToCity = toCity
-    order.Weight = weight
-    order.Length = length
-    order.Width = width
-    order.Height = height
-    order.Status = ds.StatusFormed
-    order.FormedAt = &now
-    order.IsDraft = false
+    order.FromCity = from.Name
+    order.ToCity = to.Name
+    order.FromCityID = &from.ID
+    order.ToCityID = &to.ID
+    if hasCargoItems {
+        pieces := cargoPiecesOf(order)
+        order.Weight = calculator.TotalWeight(pieces)
+        order.Volume = calculator.TotalVolume(pieces)
+        order.Length, order.Width, order.Height = calculator.MaxPieceDimensions(pieces)
+    } else {
+        order.Weight = weight
+        order.Length = length
+        order.Width = width
+        order.Height = height
+        order.Volume = length * width * height
+    }
+    if err := workflow.Apply(&order, ds.StatusFormed, actor, now); err != nil {
+        return ds.Quote{}, err
+    }
+    if order.TrackingNumber == nil {
+        number, err := newTrackingNumber()
+        if err != nil {
+            return ds.Quote{}, err
+        }
+        order.TrackingNumber = &number
+    }
     
-    return r.db.Save(&order).Error
+    // Фиксируем расчет: цена действует QuoteValidity, затем пересчитывается при завершении
+    pricing, err := r.priceLogisticRequest(order, now)
+    if err != nil {
+        return ds.Quote{}, err
+    }
+    order.TotalCost = pricing.TotalCost
+    order.TotalDays = pricing.TotalDays
+    
+    var quote ds.Quote
+    err = r.db.Transaction(func(tx *gorm.DB) error {
+        quote, err = createQuote(tx, order, pricing)
+        if err != nil {
+            return err
+        }
+        order.QuoteID = &quote.ID
+        if err := tx.Omit("Services", "CargoItems", "Legs").Save(&order).Error; err != nil {
+            return err
+        }
+        if err := recordFieldChanges(tx, actor.UserID, before, order); err != nil {
+            return err
+        }
+        return recordStatusChange(tx, actor.UserID, order.ID, before.Status, order.Status, "")
+    })
+    if err != nil {
+        return ds.Quote{}, err
+    }
+    return quote, nil
 }
 
-// CompleteLogisticRequest - завершение/отклонение заявки модератором
-func (r *Repository) CompleteLogisticRequest(orderID int, status string, moderatorID int) error {
+// CompleteLogisticRequest - завершение/отклонение заявки модератором.
+// Цена берётся из зафиксированного расчета; если он истёк и цена изменилась,
+// завершение требует подтверждения новой цены (acceptReprice).
+func (r *Repository) CompleteLogisticRequest(orderID int, status string, actor workflow.Actor, acceptReprice bool, comment string) (CompletionPricing, error) {
     if status != ds.StatusCompleted && status != ds.StatusRejected {
-        return fmt.Errorf("неверный статус для завершения")
+        return CompletionPricing{}, fmt.Errorf("неверный статус для завершения")
     }
     
     var order ds.LogisticRequest
-    err := r.db.Preload("Services.TransportService").Where("id = ?", orderID).First(&order).Error
+    err := r.db.Preload("Services.TransportService", withDeleted).Preload("Legs", orderLegsBySequence).Preload("CargoItems").
+        Where("id = ?", orderID).First(&order).Error
     if err != nil {
-        return fmt.Errorf("заявка не найдена")
+        return CompletionPricing{}, fmt.Errorf("заявка не найдена")
     }
     
-    if order.Status != ds.StatusFormed {
-        return fmt.Errorf("можно завершать только сформированные заявки")
+    if err := workflow.Check(order, status, actor); err != nil {
+        return CompletionPricing{}, err
     }
     
-    // Рассчитываем стоимость и сроки при завершении
+    before := order
+    var summary CompletionPricing
     if status == ds.StatusCompleted {
-        calc := calculator.NewDeliveryCalculator()
-        totalCost := 0.0
-        maxDays := 0
-        
-        for _, orderService := range order.Services {
-            res := calc.CalculateDelivery(orderService.TransportService, order.FromCity, order.ToCity, 
-                order.Length, order.Width, order.Height, order.Weight)
-            if res.IsValid {
-                totalCost += res.TotalCost
-                if res.DeliveryDays > maxDays {
-                    maxDays = res.DeliveryDays
-                }
-            }
+        var pricing requestPricing
+        pricing, summary, err = r.resolveCompletionPricing(order, acceptReprice)
+        if err != nil {
+            return summary, err
         }
         
-        order.TotalCost = totalCost
-        order.TotalDays = maxDays
+        // Детализация расчета по строкам заявки
+        applyQuoteLines(order.Services, pricing.Lines)
+        order.TotalCost = pricing.TotalCost
+        order.TotalDays = pricing.TotalDays
     }
     
-    now := time.Now()
-    order.Status = status
-    order.ModeratorID = &moderatorID
-    order.CompletedAt = &now
+    if err := workflow.Apply(&order, status, actor, time.Now()); err != nil {
+        return summary, err
+    }
     
-    return r.db.Save(&order).Error
+    err = r.db.Transaction(func(tx *gorm.DB) error {
+        if status == ds.StatusCompleted {
+            for i := range order.Services {
+                if err := tx.Omit("TransportService").Save(&order.Services[i]).Error; err != nil {
+                    return err
+                }
+            }
+        }
+        if err := tx.Omit("Services", "CargoItems", "Legs").Save(&order).Error; err != nil {
+            return err
+        }
+        if err := recordFieldChanges(tx, actor.UserID, before, order); err != nil {
+            return err
+        }
+        return recordStatusChange(tx, actor.UserID, order.ID, before.Status, order.Status, comment)
+    })
+    return summary, err
 }
 
-// DeleteLogisticRequest - удаление заявки (мягкое удаление)
-
-func (r *Repository) DeleteLogisticRequest(orderID int) error {
-    // Каскадное удаление автоматически удалит связанные записи в logistic_request_services
-    return r.db.Where("id = ?", orderID).Delete(&ds.LogisticRequest{}).Error
+// DeleteLogisticRequest - удаление заявки (мягкое удаление через переход в статус deleted)
+func (r *Repository) DeleteLogisticRequest(orderID int, actor workflow.Actor) error {
+    _, err := r.TransitionLogisticRequest(orderID, ds.StatusDeleted, actor, "")
+    return err
 }
 
 // GetCartIcon - получение иконки корзины (количество услуг в черновике)
 func (r *Repository) GetCartIcon(creatorID int) (int, int, error) {
     var order ds.LogisticRequest
-    err := r.db.Preload("Services").Where("creator_id = ? AND status = ? AND deleted_at IS NULL", 
+    err := r.db.Preload("Services").Where("creator_id = ? AND status = ?", 
         creatorID, ds.StatusDraft).First(&order).Error
     if err != nil {
         // Создаём черновик если нет
@@ -477,8 +669,20 @@ func (r *Repository) ClearUserDraftLogisticRequest(creatorID int) error {
 
 // ==================== М-М ЗАЯВКА-УСЛУГА ====================
 
+// ErrNotDraft - состав заявки можно менять только в черновике (после формирования цена зафиксирована)
+var ErrNotDraft = errors.New("заявка не найдена или не является черновиком")
+
+// requireDraft - проверка, что заявка является черновиком
+func (r *Repository) requireDraft(orderID int) error {
+    var order ds.LogisticRequest
+    if err := r.db.Where("id = ? AND status = ?", orderID, ds.StatusDraft).First(&order).Error; err != nil {
+        return ErrNotDraft
+    }
+    return nil
+}
+
 // AddServiceToLogisticRequest - добавление услуги в заявку-черновик
-func (r *Repository) AddServiceToLogisticRequest(orderID, serviceID int) error {
+func (r *Repository) AddServiceToLogisticRequest(orderID, serviceID, actorID int) error {
     // Проверяем что заявка - черновик
     var order ds.LogisticRequest
     err := r.db.Where("id = ? AND status = ?", orderID, ds.StatusDraft).First(&order).Error
@@ -493,12 +697,38 @@ func (r *Repository) AddServiceToLogisticRequest(orderID, serviceID int) error {
     }
     
     // Проверяем не добавлена ли уже
-    var existing ds.LogisticRequestService
-    err = r.db.Where("logistic_request_id = ? AND transport_service_id = ?", orderID, serviceID).First(&existing).Error
-    if err == nil {
+    existing, found := findRequestLine(r.db, orderID, serviceID)
+    if found && existing.DeletedAt.Valid {
+        // Строка была удалена — восстанавливаем её как новую
+        existing.DeletedAt = gorm.DeletedAt{}
+        existing.Quantity = 1
+        return r.db.Transaction(func(tx *gorm.DB) error {
+            if err := tx.Unscoped().Save(&existing).Error; err != nil {
+                return err
+            }
+            return recordEvent(tx, actorID, ds.LogisticRequestEvent{
+                LogisticRequestID: orderID,
+                Type:              ds.EventServiceAdded,
+                SubjectID:         &serviceID,
+            })
+        })
+    }
+    if found {
         // Увеличиваем количество
         existing.Quantity++
-        return r.db.Save(&existing).Error
+        return r.db.Transaction(func(tx *gorm.DB) error {
+            if err := tx.Save(&existing).Error; err != nil {
+                return err
+            }
+            return recordEvent(tx, actorID, ds.LogisticRequestEvent{
+                LogisticRequestID: orderID,
+                Type:              ds.EventServiceUpdated,
+                SubjectID:         &serviceID,
+                Field:             "quantity",
+                OldValue:          strconv.Itoa(existing.Quantity - 1),
+                NewValue:          strconv.Itoa(existing.Quantity),
+            })
+        })
     }
     
     // Добавляем новую
@@ -507,40 +737,96 @@ func (r *Repository) AddServiceToLogisticRequest(orderID, serviceID int) error {
         TransportServiceID: serviceID,
         Quantity:  1,
     }
-    return r.db.Create(&orderService).Error
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Create(&orderService).Error; err != nil {
+            return err
+        }
+        return recordEvent(tx, actorID, ds.LogisticRequestEvent{
+            LogisticRequestID: orderID,
+            Type:              ds.EventServiceAdded,
+            SubjectID:         &serviceID,
+        })
+    })
 }
 
-// RemoveServiceFromLogisticRequest - удаление услуги из заявки
-func (r *Repository) RemoveServiceFromLogisticRequest(orderID, serviceID int) error {
+// RemoveServiceFromLogisticRequest - удаление услуги из заявки-черновика
+func (r *Repository) RemoveServiceFromLogisticRequest(orderID, serviceID, actorID int) error {
+    if err := r.requireDraft(orderID); err != nil {
+        return err
+    }
+
     var orderService ds.LogisticRequestService
     err := r.db.Where("logistic_request_id = ? AND transport_service_id = ?", orderID, serviceID).First(&orderService).Error
     if err != nil {
         return fmt.Errorf("услуга не найдена в заявке")
     }
     
-    return r.db.Delete(&orderService).Error
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Delete(&orderService).Error; err != nil {
+            return err
+        }
+        return recordEvent(tx, actorID, ds.LogisticRequestEvent{
+            LogisticRequestID: orderID,
+            Type:              ds.EventServiceRemoved,
+            SubjectID:         &serviceID,
+            OldValue:          strconv.Itoa(orderService.Quantity),
+        })
+    })
 }
 
-// UpdateLogisticRequestService - обновление количества/порядка в м-м
-func (r *Repository) UpdateLogisticRequestService(orderID, serviceID int, quantity, orderNum int, comment string) error {
+// UpdateLogisticRequestService - обновление количества/порядка в м-м (только в черновике)
+func (r *Repository) UpdateLogisticRequestService(orderID, serviceID int, quantity, orderNum int, comment string, actorID int) error {
+    if err := r.requireDraft(orderID); err != nil {
+        return err
+    }
+
     var orderService ds.LogisticRequestService
     err := r.db.Where("logistic_request_id = ? AND transport_service_id = ?", orderID, serviceID).First(&orderService).Error
     if err != nil {
         return fmt.Errorf("услуга не найдена в заявке")
     }
     
+    changes := [][3]string{
+        {"quantity", strconv.Itoa(orderService.Quantity), strconv.Itoa(quantity)},
+        {"sort_order", strconv.Itoa(orderService.SortOrder), strconv.Itoa(orderNum)},
+        {"comment", orderService.Comment, comment},
+    }
     orderService.Quantity = quantity
     orderService.SortOrder = orderNum
     orderService.Comment = comment
     
-    return r.db.Save(&orderService).Error
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Save(&orderService).Error; err != nil {
+            return err
+        }
+        for _, c := range changes {
+            if c[1] == c[2] {
+                continue
+            }
+            err := recordEvent(tx, actorID, ds.LogisticRequestEvent{
+                LogisticRequestID: orderID,
+                Type:              ds.EventServiceUpdated,
+                SubjectID:         &serviceID,
+                Field:             c[0],
+                OldValue:          c[1],
+                NewValue:          c[2],
+            })
+            if err != nil {
+                return err
+            }
+        }
+        return nil
+    })
 }
 
 
 // ensureGuestDraftLogisticRequest - гарантирует наличие черновика логистической заявки для sessionID (guest/web)
 func (r *Repository) ensureGuestDraftLogisticRequest(sessionID string) (int, error) {
+    if sessionID == "" {
+        return 0, fmt.Errorf("не указана гостевая сессия")
+    }
     var order ds.LogisticRequest
-    if err := r.db.Where("session_id = ? AND is_draft = ? AND deleted_at IS NULL", sessionID, true).First(&order).Error; err != nil {
+    if err := r.db.Where("session_id = ? AND status = ?", sessionID, ds.StatusDraft).First(&order).Error; err != nil {
         // создаём с системным создателем
         order = ds.LogisticRequest{
             SessionID: sessionID, 
@@ -555,15 +841,21 @@ func (r *Repository) ensureGuestDraftLogisticRequest(sessionID string) (int, err
     return order.ID, nil
 }
 
+// touchGuestDraft - отметка активности гостевого черновика (по updated_at чистятся брошенные черновики)
+func (r *Repository) touchGuestDraft(orderID int) {
+    r.db.Model(&ds.LogisticRequest{}).Where("id = ?", orderID).Update("updated_at", time.Now())
+}
+
 // AddTransportServiceToGuestDraftLogisticRequest - добавляет транспортную услугу в черновик заявки (guest)
-func (r *Repository) AddTransportServiceToGuestDraftLogisticRequest(serviceID int) error {
+func (r *Repository) AddTransportServiceToGuestDraftLogisticRequest(sessionID string, serviceID int) error {
     // проверяем услугу
     if _, err := r.GetTransportService(serviceID); err != nil {
         return fmt.Errorf("услуга не найдена")
     }
     // берём черновик логистической заявки
-    orderID, err := r.ensureGuestDraftLogisticRequest("guest")
+    orderID, err := r.ensureGuestDraftLogisticRequest(sessionID)
     if err != nil { return err }
+    defer r.touchGuestDraft(orderID)
 
     // upsert в logistic_request_services
     // используем нативное подключение для ON CONFLICT
@@ -572,45 +864,48 @@ func (r *Repository) AddTransportServiceToGuestDraftLogisticRequest(serviceID in
         INSERT INTO logistic_request_services(logistic_request_id, transport_service_id, quantity)
         VALUES ($1, $2, 1)
         ON CONFLICT (logistic_request_id, transport_service_id)
-        DO UPDATE SET quantity = logistic_request_services.quantity + 1
+        DO UPDATE SET quantity = CASE WHEN logistic_request_services.deleted_at IS NULL
+                                      THEN logistic_request_services.quantity + 1 ELSE 1 END,
+                      deleted_at = NULL
     `, orderID, serviceID)
     return err
 }
 
 // RemoveTransportServiceFromGuestDraftLogisticRequest - уменьшает количество услуги в черновике или удаляет строку
-func (r *Repository) RemoveTransportServiceFromGuestDraftLogisticRequest(serviceID int) error {
-    orderID, err := r.ensureGuestDraftLogisticRequest("guest")
+func (r *Repository) RemoveTransportServiceFromGuestDraftLogisticRequest(sessionID string, serviceID int) error {
+    orderID, err := r.ensureGuestDraftLogisticRequest(sessionID)
     if err != nil { return err }
+    defer r.touchGuestDraft(orderID)
 
     sqlDB, err := r.db.DB(); if err != nil { return err }
     // уменьшаем qty если >1, иначе удаляем
     var qty int
-    err = sqlDB.QueryRow(`SELECT quantity FROM logistic_request_services WHERE logistic_request_id=$1 AND transport_service_id=$2`, orderID, serviceID).Scan(&qty)
+    err = sqlDB.QueryRow(`SELECT quantity FROM logistic_request_services WHERE logistic_request_id=$1 AND transport_service_id=$2 AND deleted_at IS NULL`, orderID, serviceID).Scan(&qty)
     if err == sql.ErrNoRows { return fmt.Errorf("услуга не найдена в черновике заявки") }
     if err != nil { return err }
 
     if qty > 1 {
         _, err = sqlDB.Exec(`UPDATE logistic_request_services SET quantity = quantity - 1 WHERE logistic_request_id=$1 AND transport_service_id=$2`, orderID, serviceID)
     } else {
-        _, err = sqlDB.Exec(`DELETE FROM logistic_request_services WHERE logistic_request_id=$1 AND transport_service_id=$2`, orderID, serviceID)
+        _, err = sqlDB.Exec(`UPDATE logistic_request_services SET deleted_at = NOW() WHERE logistic_request_id=$1 AND transport_service_id=$2`, orderID, serviceID)
     }
     return err
 }
 
 // GetGuestDraftLogisticRequestView - получение представления черновика заявки (guest)
-func (r *Repository) GetGuestDraftLogisticRequestView() (ds.DraftLogisticRequest, error) {
-    orderID, err := r.ensureGuestDraftLogisticRequest("guest")
+func (r *Repository) GetGuestDraftLogisticRequestView(sessionID string) (ds.DraftLogisticRequest, error) {
+    orderID, err := r.ensureGuestDraftLogisticRequest(sessionID)
     if err != nil { return ds.DraftLogisticRequest{}, err }
     var items []ds.DraftLogisticRequestService
     if err := r.db.Where("logistic_request_id = ?", orderID).Find(&items).Error; err != nil {
         return ds.DraftLogisticRequest{}, err
     }
-    return ds.DraftLogisticRequest{ID: orderID, SessionID: "guest", IsDraft: true, Services: items}, nil
+    return ds.DraftLogisticRequest{ID: orderID, SessionID: sessionID, IsDraft: true, Services: items}, nil
 }
 
 // GetGuestDraftLogisticRequestServices - услуги в черновике заявки (guest) с полной информацией
-func (r *Repository) GetGuestDraftLogisticRequestServices() ([]ds.TransportService, error) {
-    orderID, err := r.ensureGuestDraftLogisticRequest("guest")
+func (r *Repository) GetGuestDraftLogisticRequestServices(sessionID string) ([]ds.TransportService, error) {
+    orderID, err := r.ensureGuestDraftLogisticRequest(sessionID)
     if err != nil { 
         logrus.Errorf("GetGuestDraftLogisticRequestServices: failed to ensure draft request: %v", err)
         return nil, err 
@@ -635,61 +930,20 @@ func (r *Repository) GetGuestDraftLogisticRequestServices() ([]ds.TransportServi
 }
 
 // GetGuestDraftLogisticRequestServiceCount - общее количество услуг в черновике заявки (guest)
-func (r *Repository) GetGuestDraftLogisticRequestServiceCount() int {
-    orderID, err := r.ensureGuestDraftLogisticRequest("guest")
+func (r *Repository) GetGuestDraftLogisticRequestServiceCount(sessionID string) int {
+    orderID, err := r.ensureGuestDraftLogisticRequest(sessionID)
     if err != nil { return 0 }
     sqlDB, err := r.db.DB(); if err != nil { return 0 }
     var count sql.NullInt64
-    _ = sqlDB.QueryRow(`SELECT COALESCE(SUM(quantity),0) FROM logistic_request_services WHERE logistic_request_id=$1`, orderID).Scan(&count)
+    _ = sqlDB.QueryRow(`SELECT COALESCE(SUM(quantity),0) FROM logistic_request_services WHERE logistic_request_id=$1 AND deleted_at IS NULL`, orderID).Scan(&count)
     if count.Valid { return int(count.Int64) }
     return 0
 }
 
 // ClearGuestDraftLogisticRequest - очистка черновика заявки (guest) (удаление всех строк услуг)
-func (r *Repository) ClearGuestDraftLogisticRequest() {
-    orderID, err := r.ensureGuestDraftLogisticRequest("guest")
+func (r *Repository) ClearGuestDraftLogisticRequest(sessionID string) {
+    orderID, err := r.ensureGuestDraftLogisticRequest(sessionID)
     if err != nil { return }
     r.db.Where("logistic_request_id = ?", orderID).Delete(&ds.DraftLogisticRequestService{})
-}
-
-// UpdateLogisticRequestStatusWithCursor - обновление статуса заказа через курсор без ORM
-func (r *Repository) UpdateLogisticRequestStatusWithCursor(orderID int, newStatus string) error {
-	// Получаем нативное подключение к БД из GORM
-	sqlDB, err := r.db.DB()
-	if err != nil {
-		return fmt.Errorf("failed to get sql.DB: %w", err)
-	}
-
-	// Подготавливаем запрос с курсором
-	query := `
-		UPDATE logistic_requests 
-		SET status = $1 
-		WHERE id = $2
-		RETURNING id, status, from_city, to_city
-	`
-
-	// Выполняем запрос через курсор
-	rows, err := sqlDB.Query(query, newStatus, orderID)
-	if err != nil {
-		return fmt.Errorf("failed to execute query: %w", err)
-	}
-	defer rows.Close()
-
-	// Обрабатываем результат через курсор
-	if rows.Next() {
-		var id int
-		var status, fromCity, toCity string
-		
-		err := rows.Scan(&id, &status, &fromCity, &toCity)
-		if err != nil {
-			return fmt.Errorf("failed to scan row: %w", err)
-		}
-		
-		// Логируем обновление
-		fmt.Printf("LogisticRequest %d status updated to: %s (Route: %s -> %s)\n", id, status, fromCity, toCity)
-	} else {
-		return fmt.Errorf("order with id %d not found", orderID)
-	}
-
-	return nil
+    r.touchGuestDraft(orderID)
 }
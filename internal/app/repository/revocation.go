@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"rip-go-app/internal/app/ds"
+)
+
+// ==================== ОТЗЫВ ТОКЕНОВ ====================
+// Хранилище отзыва токенов в Postgres (auth.RevocationStore) для установки без Redis
+
+// RevokeToken - отзыв токена до истечения его срока
+func (r *Repository) RevokeToken(jti string, ttl time.Duration) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&ds.RevokedToken{JTI: jti, ExpiresAt: time.Now().Add(ttl)}).Error
+}
+
+// IsTokenRevoked - отозван ли токен
+func (r *Repository) IsTokenRevoked(jti string) (bool, error) {
+	var count int64
+	err := r.db.Model(&ds.RevokedToken{}).Where("jti = ? AND expires_at > ?", jti, time.Now()).Count(&count).Error
+	return count > 0, err
+}
+
+// RevokeSessionTokens - отзыв всех токенов сессии до истечения срока её access токенов
+func (r *Repository) RevokeSessionTokens(sessionID string, ttl time.Duration) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "session_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"expires_at"}),
+	}).Create(&ds.RevokedSession{SessionID: sessionID, ExpiresAt: time.Now().Add(ttl)}).Error
+}
+
+// IsSessionRevoked - завершена ли сессия
+func (r *Repository) IsSessionRevoked(sessionID string) (bool, error) {
+	var count int64
+	err := r.db.Model(&ds.RevokedSession{}).Where("session_id = ? AND expires_at > ?", sessionID, time.Now()).Count(&count).Error
+	return count > 0, err
+}
+
+// TokenGeneration - текущее поколение токенов пользователя
+func (r *Repository) TokenGeneration(userUUID string) (int, error) {
+	var user ds.User
+	if err := r.db.Select("token_generation").Where("uuid = ?", userUUID).First(&user).Error; err != nil {
+		return 0, fmt.Errorf("пользователь не найден")
+	}
+	return user.TokenGeneration, nil
+}
+
+// BumpTokenGeneration - новое поколение токенов пользователя (все выданные ранее недействительны)
+func (r *Repository) BumpTokenGeneration(userUUID string) (int, error) {
+	var user ds.User
+	res := r.db.Model(&user).Clauses(clause.Returning{Columns: []clause.Column{{Name: "token_generation"}}}).
+		Where("uuid = ?", userUUID).Update("token_generation", gorm.Expr("token_generation + 1"))
+	if res.Error != nil {
+		return 0, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return 0, fmt.Errorf("пользователь не найден")
+	}
+	return user.TokenGeneration, nil
+}
+
+// PurgeRevokedTokens - удаление записей об отозванных токенах и завершённых сессиях, срок которых истёк
+func (r *Repository) PurgeRevokedTokens() (int64, error) {
+	now := time.Now()
+	res := r.db.Where("expires_at < ?", now).Delete(&ds.RevokedToken{})
+	if res.Error != nil {
+		return 0, res.Error
+	}
+	sessions := r.db.Where("expires_at < ?", now).Delete(&ds.RevokedSession{})
+	return res.RowsAffected + sessions.RowsAffected, sessions.Error
+}
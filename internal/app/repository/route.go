@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"rip-go-app/internal/app/calculator"
+	"rip-go-app/internal/app/ds"
+)
+
+// ==================== МУЛЬТИМОДАЛЬНЫЕ МАРШРУТЫ ====================
+
+// RouteLegInput - плечо маршрута, как его передаёт клиент
+type RouteLegInput struct {
+	TransportServiceID int
+	FromCity           string
+	ToCity             string
+}
+
+// BuildRoute - подготовка плеч маршрута для калькулятора: загрузка услуг и приведение городов к справочнику
+func (r *Repository) BuildRoute(inputs []RouteLegInput) ([]calculator.RouteLeg, error) {
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("маршрут не содержит ни одного плеча")
+	}
+
+	legs := make([]calculator.RouteLeg, 0, len(inputs))
+	for i, in := range inputs {
+		svc, err := r.GetTransportService(in.TransportServiceID)
+		if err != nil {
+			return nil, fmt.Errorf("плечо %d: услуга %d не найдена", i+1, in.TransportServiceID)
+		}
+		from, err := r.FindCity(in.FromCity)
+		if err != nil {
+			return nil, fmt.Errorf("плечо %d: %s", i+1, err.Error())
+		}
+		to, err := r.FindCity(in.ToCity)
+		if err != nil {
+			return nil, fmt.Errorf("плечо %d: %s", i+1, err.Error())
+		}
+		legs = append(legs, calculator.RouteLeg{Service: svc, FromCity: from.Name, ToCity: to.Name})
+	}
+	return legs, nil
+}
+
+// CalculateRoute - расчет маршрута из нескольких плеч по местам груза по текущим тарифам
+func (r *Repository) CalculateRoute(inputs []RouteLegInput, pieces []calculator.CargoPiece) (calculator.RouteResult, error) {
+	return r.CalculateRouteAt(inputs, pieces, time.Now())
+}
+
+// CalculateRouteAt - расчет маршрута по тарифам, действовавшим в момент at
+func (r *Repository) CalculateRouteAt(inputs []RouteLegInput, pieces []calculator.CargoPiece, at time.Time) (calculator.RouteResult, error) {
+	legs, err := r.BuildRoute(inputs)
+	if err != nil {
+		return calculator.RouteResult{}, err
+	}
+	for i := range legs {
+		legs[i].Service = r.serviceAt(legs[i].Service, at)
+	}
+	calc := calculator.NewDeliveryCalculator(r)
+	return calc.CalculateRoute(legs, pieces), nil
+}
+
+// SetLogisticRequestRoute - сохранение выбранного маршрута в заявке-черновике (заменяет прежние плечи)
+func (r *Repository) SetLogisticRequestRoute(orderID int, inputs []RouteLegInput, actorID int) (calculator.RouteResult, error) {
+	var order ds.LogisticRequest
+	if err := r.db.Preload("CargoItems").Preload("Legs", orderLegsBySequence).Where("id = ?", orderID).First(&order).Error; err != nil {
+		return calculator.RouteResult{}, fmt.Errorf("заявка не найдена")
+	}
+	if order.Status != ds.StatusDraft {
+		return calculator.RouteResult{}, fmt.Errorf("маршрут можно менять только в черновике")
+	}
+	if len(order.CargoItems) == 0 && (order.Weight <= 0 || order.Length <= 0 || order.Width <= 0 || order.Height <= 0) {
+		return calculator.RouteResult{}, fmt.Errorf("не заполнены параметры груза")
+	}
+
+	res, err := r.CalculateRoute(inputs, cargoPiecesOf(order))
+	if err != nil {
+		return calculator.RouteResult{}, err
+	}
+	if !res.IsValid {
+		return res, fmt.Errorf("%s", res.ErrorMessage)
+	}
+
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("logistic_request_id = ?", orderID).Delete(&ds.LogisticRequestLeg{}).Error; err != nil {
+			return err
+		}
+		for _, leg := range res.Legs {
+			row := ds.LogisticRequestLeg{
+				LogisticRequestID:  orderID,
+				Sequence:           leg.Sequence,
+				TransportServiceID: leg.TransportServiceID,
+				FromCity:           leg.FromCity,
+				ToCity:             leg.ToCity,
+				Distance:           leg.Distance,
+				DeliveryDays:       leg.DeliveryDays,
+				Cost:               leg.Cost,
+			}
+			if from, err := r.FindCity(leg.FromCity); err == nil {
+				row.FromCityID = &from.ID
+			}
+			if to, err := r.FindCity(leg.ToCity); err == nil {
+				row.ToCityID = &to.ID
+			}
+			if err := tx.Create(&row).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := recordEvent(tx, actorID, ds.LogisticRequestEvent{
+			LogisticRequestID: orderID,
+			Type:              ds.EventRouteChanged,
+			Field:             "legs",
+			OldValue:          describeRoute(routeInputsFromLegs(order.Legs)),
+			NewValue:          describeRoute(routeInputsFromResult(res)),
+		}); err != nil {
+			return err
+		}
+
+		// Начало и конец маршрута — города заявки
+		first, last := res.Legs[0], res.Legs[len(res.Legs)-1]
+		return tx.Model(&ds.LogisticRequest{}).Where("id = ?", orderID).Updates(map[string]interface{}{
+			"from_city":  first.FromCity,
+			"to_city":    last.ToCity,
+			"total_cost": res.TotalCost,
+			"total_days": res.DeliveryDays,
+		}).Error
+	})
+	if err != nil {
+		return calculator.RouteResult{}, err
+	}
+	return res, nil
+}
+
+// orderLegsBySequence - плечи маршрута в порядке следования
+func orderLegsBySequence(db *gorm.DB) *gorm.DB {
+	return db.Order("sequence")
+}
+
+// routeInputsFromLegs - плечи заявки в виде входных данных для пересчёта
+func routeInputsFromLegs(legs []ds.LogisticRequestLeg) []RouteLegInput {
+	inputs := make([]RouteLegInput, 0, len(legs))
+	for _, leg := range legs {
+		inputs = append(inputs, RouteLegInput{
+			TransportServiceID: leg.TransportServiceID,
+			FromCity:           leg.FromCity,
+			ToCity:             leg.ToCity,
+		})
+	}
+	return inputs
+}
+
+// routeInputsFromResult - рассчитанные плечи в виде входных данных (с городами из справочника)
+func routeInputsFromResult(res calculator.RouteResult) []RouteLegInput {
+	inputs := make([]RouteLegInput, 0, len(res.Legs))
+	for _, leg := range res.Legs {
+		inputs = append(inputs, RouteLegInput{
+			TransportServiceID: leg.TransportServiceID,
+			FromCity:           leg.FromCity,
+			ToCity:             leg.ToCity,
+		})
+	}
+	return inputs
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"rip-go-app/internal/app/ds"
+)
+
+// ==================== СЕССИИ (REFRESH ТОКЕНЫ) ====================
+
+// Ошибки обмена refresh токена (проверяются через errors.Is)
+var (
+	ErrRefreshTokenInvalid = errors.New("refresh токен недействителен")
+	ErrRefreshTokenReused  = errors.New("повторное использование refresh токена, сессия отозвана")
+	ErrSessionNotFound     = errors.New("сессия не найдена")
+)
+
+// Session - активная сессия пользователя (семейство refresh токенов одного входа)
+type Session struct {
+	ID         string    `json:"id"` // ID семейства
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`   // вход
+	LastUsedAt time.Time `json:"last_used_at"` // последнее обновление токенов
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current" gorm:"-"`
+}
+
+// activeRefreshToken - условие на действующий (не использованный, не отозванный, не истёкший) токен
+func activeRefreshToken(db *gorm.DB, now time.Time) *gorm.DB {
+	return db.Where("used_at IS NULL AND revoked_at IS NULL AND expires_at > ?", now)
+}
+
+// CreateRefreshToken - сохранение выданного refresh токена
+func (r *Repository) CreateRefreshToken(token *ds.RefreshToken) error {
+	return r.db.Create(token).Error
+}
+
+// RotateRefreshToken - обмен refresh токена jti на следующий токен next того же семейства.
+// Токен обменивается один раз: предъявление уже обменянного токена означает его кражу,
+// поэтому всё семейство отзывается и возвращается ErrRefreshTokenReused.
+func (r *Repository) RotateRefreshToken(jti string, next *ds.RefreshToken) error {
+	now := time.Now()
+	var reused bool
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var current ds.RefreshToken
+		if err := tx.Where("id = ?", jti).First(&current).Error; err != nil {
+			return ErrRefreshTokenInvalid
+		}
+		if current.UsedAt != nil {
+			reused = true
+			return nil
+		}
+		if current.RevokedAt != nil || !current.ExpiresAt.After(now) {
+			return ErrRefreshTokenInvalid
+		}
+
+		// Условие на used_at защищает от одновременного обмена одного токена
+		res := activeRefreshToken(tx.Model(&ds.RefreshToken{}).Where("id = ?", jti), now).
+			Updates(map[string]interface{}{"used_at": now, "replaced_by": next.ID})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			reused = true
+			return nil
+		}
+
+		next.FamilyID = current.FamilyID
+		next.UserID = current.UserID
+		return tx.Create(next).Error
+	})
+	if err != nil {
+		return err
+	}
+	if reused {
+		if err := r.revokeFamilies("id = ?", jti); err != nil {
+			return err
+		}
+		return ErrRefreshTokenReused
+	}
+	return nil
+}
+
+// revokeFamilies - отзыв всех токенов семейств, к которым относятся токены, выбранные условием
+func (r *Repository) revokeFamilies(tokens string, args ...interface{}) error {
+	return r.db.Model(&ds.RefreshToken{}).
+		Where("revoked_at IS NULL AND family_id IN (SELECT family_id FROM refresh_tokens WHERE "+tokens+")", args...).
+		Update("revoked_at", time.Now()).Error
+}
+
+// GetUserSessions - активные сессии пользователя, последние использованные — первыми
+func (r *Repository) GetUserSessions(userID int) ([]Session, error) {
+	var sessions []Session
+	err := r.db.Model(&ds.RefreshToken{}).
+		Select(`family_id AS id, MIN(issued_at) AS created_at, MAX(issued_at) AS last_used_at,
+			(ARRAY_AGG(user_agent ORDER BY issued_at DESC))[1] AS user_agent,
+			(ARRAY_AGG(ip ORDER BY issued_at DESC))[1] AS ip,
+			MAX(expires_at) AS expires_at`).
+		Where("user_id = ?", userID).
+		Group("family_id").
+		Having("BOOL_OR(used_at IS NULL AND revoked_at IS NULL AND expires_at > ?)", time.Now()).
+		Order("last_used_at DESC").
+		Scan(&sessions).Error
+	return sessions, err
+}
+
+// RevokeSession - завершение сессии пользователя (отзыв семейства refresh токенов)
+func (r *Repository) RevokeSession(userID int, familyID string) error {
+	var count int64
+	err := activeRefreshToken(r.db.Model(&ds.RefreshToken{}), time.Now()).
+		Where("user_id = ? AND family_id = ?", userID, familyID).Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrSessionNotFound
+	}
+	return r.revokeFamilies("family_id = ?", familyID)
+}
+
+// RevokeOtherSessions - завершение всех сессий пользователя, кроме keepFamilyID.
+// Возвращает ID завершённых сессий.
+func (r *Repository) RevokeOtherSessions(userID int, keepFamilyID string) ([]string, error) {
+	var families []string
+	err := activeRefreshToken(r.db.Model(&ds.RefreshToken{}), time.Now()).
+		Where("user_id = ? AND family_id != ?", userID, keepFamilyID).
+		Distinct().Pluck("family_id", &families).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := r.revokeFamilies("user_id = ? AND family_id != ?", userID, keepFamilyID); err != nil {
+		return nil, err
+	}
+	return families, nil
+}
+
+// PurgeExpiredRefreshTokens - удаление истёкших refresh токенов (после истечения они не нужны и для выявления повторов)
+func (r *Repository) PurgeExpiredRefreshTokens() (int64, error) {
+	res := r.db.Where("expires_at < ?", time.Now()).Delete(&ds.RefreshToken{})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"rip-go-app/internal/app/ds"
+	"rip-go-app/internal/app/workflow"
+)
+
+// ==================== МЯГКОЕ УДАЛЕНИЕ ====================
+
+// withDeleted - подгрузка связи вместе с мягко удалёнными записями
+// (удалённая услуга остаётся в заявках, где она уже использована)
+func withDeleted(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
+}
+
+// findRequestLine - строка услуги в заявке, в том числе мягко удалённая
+// (пара заявка-услуга уникальна, поэтому удалённую строку восстанавливают, а не создают заново)
+func findRequestLine(tx *gorm.DB, orderID, serviceID int) (ds.LogisticRequestService, bool) {
+	var line ds.LogisticRequestService
+	found := tx.Unscoped().Where("logistic_request_id = ? AND transport_service_id = ?", orderID, serviceID).
+		Limit(1).Find(&line).RowsAffected > 0
+	return line, found
+}
+
+// RestoreTransportService - восстановление мягко удалённой услуги
+func (r *Repository) RestoreTransportService(id int) (ds.TransportService, error) {
+	var service ds.TransportService
+	if err := r.db.Unscoped().Where("id = ?", id).First(&service).Error; err != nil {
+		return ds.TransportService{}, fmt.Errorf("услуга не найдена")
+	}
+	if !service.DeletedAt.Valid {
+		return ds.TransportService{}, fmt.Errorf("услуга не удалена")
+	}
+	if err := r.db.Unscoped().Model(&service).Update("deleted_at", nil).Error; err != nil {
+		return ds.TransportService{}, err
+	}
+	service.DeletedAt = gorm.DeletedAt{}
+	return service, nil
+}
+
+// RestoreLogisticRequest - восстановление удалённой заявки в черновик (переход deleted → draft через workflow).
+// У создателя не может быть двух черновиков, поэтому при наличии действующего восстановление отклоняется.
+func (r *Repository) RestoreLogisticRequest(orderID int, actor workflow.Actor) (ds.LogisticRequest, error) {
+	var order ds.LogisticRequest
+	if err := r.db.Unscoped().Where("id = ?", orderID).First(&order).Error; err != nil {
+		return ds.LogisticRequest{}, fmt.Errorf("заявка не найдена")
+	}
+	if !order.DeletedAt.Valid {
+		return ds.LogisticRequest{}, fmt.Errorf("заявка не удалена")
+	}
+	if _, err := r.GetDraftLogisticRequest(order.CreatorID); err == nil {
+		return ds.LogisticRequest{}, fmt.Errorf("у создателя заявки уже есть черновик")
+	}
+
+	from := order.Status
+	if err := workflow.Apply(&order, ds.StatusDraft, actor, time.Now()); err != nil {
+		return ds.LogisticRequest{}, err
+	}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := saveStatus(tx, &order); err != nil {
+			return err
+		}
+		return recordStatusChange(tx, actor.UserID, order.ID, from, order.Status, "восстановлена из удалённых")
+	})
+	if err != nil {
+		return ds.LogisticRequest{}, err
+	}
+	return order, nil
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"rip-go-app/internal/app/ds"
+	"rip-go-app/internal/app/workflow"
+)
+
+// ==================== СТАТУСЫ ЗАЯВОК ====================
+
+// statusColumns - поля заявки, которые меняются при переходе статуса
+var statusColumns = []string{
+	"status", "is_draft", "moderator_id",
+	"formed_at", "completed_at", "shipped_at", "delivered_at", "cancelled_at", "deleted_at",
+}
+
+// saveStatus - сохранение статуса заявки и полей, изменённых переходом
+func saveStatus(tx *gorm.DB, order *ds.LogisticRequest) error {
+	// Unscoped: восстановление из deleted обновляет мягко удалённую заявку
+	return tx.Unscoped().Model(order).Select(statusColumns).Updates(order).Error
+}
+
+// TransitionLogisticRequest - перевод заявки в новый статус по правилам workflow с записью в журнал.
+// Формирование и завершение требуют расчета и выполняются через FormLogisticRequest/CompleteLogisticRequest.
+func (r *Repository) TransitionLogisticRequest(orderID int, to string, actor workflow.Actor, comment string) (ds.LogisticRequest, error) {
+	if to == ds.StatusFormed || to == ds.StatusCompleted || to == ds.StatusRejected {
+		return ds.LogisticRequest{}, fmt.Errorf("переход в статус %s выполняется отдельной операцией", to)
+	}
+
+	var order ds.LogisticRequest
+	if err := r.db.Where("id = ?", orderID).First(&order).Error; err != nil {
+		return ds.LogisticRequest{}, fmt.Errorf("заявка не найдена")
+	}
+	from := order.Status
+	if err := workflow.Apply(&order, to, actor, time.Now()); err != nil {
+		return ds.LogisticRequest{}, err
+	}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := saveStatus(tx, &order); err != nil {
+			return err
+		}
+		return recordStatusChange(tx, actor.UserID, order.ID, from, to, comment)
+	})
+	if err != nil {
+		return ds.LogisticRequest{}, err
+	}
+	return order, nil
+}
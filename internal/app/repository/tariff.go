@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"rip-go-app/internal/app/ds"
+)
+
+// ==================== ВЕРСИИ ТАРИФОВ ====================
+
+// GetServiceTariffs - история версий тарифа услуги (включая запланированные)
+func (r *Repository) GetServiceTariffs(serviceID int) ([]ds.ServiceTariff, error) {
+	var tariffs []ds.ServiceTariff
+	err := r.db.Where("transport_service_id = ?", serviceID).Order("valid_from").Find(&tariffs).Error
+	return tariffs, err
+}
+
+// GetServiceTariffAt - версия тарифа услуги, действовавшая в указанный момент
+func (r *Repository) GetServiceTariffAt(serviceID int, at time.Time) (ds.ServiceTariff, error) {
+	var tariff ds.ServiceTariff
+	err := r.db.Where("transport_service_id = ? AND valid_from <= ? AND (valid_to IS NULL OR valid_to > ?)", serviceID, at, at).
+		Order("valid_from DESC").First(&tariff).Error
+	if err != nil {
+		return ds.ServiceTariff{}, fmt.Errorf("тариф услуги %d на %s не найден", serviceID, at.Format("2006-01-02"))
+	}
+	return tariff, nil
+}
+
+// serviceAt - услуга с тарифом на указанный момент; без истории версий используется текущий тариф
+func (r *Repository) serviceAt(service ds.TransportService, at time.Time) ds.TransportService {
+	tariff, err := r.GetServiceTariffAt(service.ID, at)
+	if err != nil {
+		return service
+	}
+	return tariff.ApplyTo(service)
+}
+
+// ErrTariffClash - на дату начала уже есть версия тарифа
+var ErrTariffClash = errors.New("на эту дату уже есть версия тарифа")
+
+// ServiceTariffBase - основа для новой версии тарифа: версия, действующая на указанный момент,
+// а без истории версий — текущий тариф из карточки услуги
+func (r *Repository) ServiceTariffBase(serviceID int, at time.Time) (ds.ServiceTariff, error) {
+	service, err := r.GetTransportService(serviceID)
+	if err != nil {
+		return ds.ServiceTariff{}, err
+	}
+	tariff, err := r.GetServiceTariffAt(serviceID, at)
+	if err != nil {
+		tariff = ds.SnapshotTariff(service)
+	}
+	tariff.ValidFrom = at
+	return tariff, nil
+}
+
+// insertServiceTariff - добавление версии тарифа, начинающей действовать с tariff.ValidFrom.
+// Предыдущая версия закрывается датой начала новой, новая действует до следующей запланированной.
+func insertServiceTariff(tx *gorm.DB, tariff *ds.ServiceTariff) error {
+	var clash int64
+	if err := tx.Model(&ds.ServiceTariff{}).
+		Where("transport_service_id = ? AND valid_from = ?", tariff.TransportServiceID, tariff.ValidFrom).
+		Count(&clash).Error; err != nil {
+		return err
+	}
+	if clash > 0 {
+		return ErrTariffClash
+	}
+
+	var maxVersion int
+	if err := tx.Model(&ds.ServiceTariff{}).Select("COALESCE(MAX(version), 0)").
+		Where("transport_service_id = ?", tariff.TransportServiceID).Scan(&maxVersion).Error; err != nil {
+		return err
+	}
+
+	var next []ds.ServiceTariff
+	if err := tx.Where("transport_service_id = ? AND valid_from > ?", tariff.TransportServiceID, tariff.ValidFrom).
+		Order("valid_from").Limit(1).Find(&next).Error; err != nil {
+		return err
+	}
+	if len(next) > 0 {
+		validTo := next[0].ValidFrom
+		tariff.ValidTo = &validTo
+	} else {
+		tariff.ValidTo = nil
+	}
+
+	// Закрываем версию, действующую на момент начала новой
+	if err := tx.Model(&ds.ServiceTariff{}).
+		Where("transport_service_id = ? AND valid_from < ? AND (valid_to IS NULL OR valid_to > ?)",
+			tariff.TransportServiceID, tariff.ValidFrom, tariff.ValidFrom).
+		Update("valid_to", tariff.ValidFrom).Error; err != nil {
+		return err
+	}
+
+	tariff.ID = 0
+	tariff.Version = maxVersion + 1
+	tariff.CreatedAt = time.Time{}
+	return tx.Create(tariff).Error
+}
+
+// ScheduleServiceTariff - планирование изменения тарифа услуги на будущую дату
+func (r *Repository) ScheduleServiceTariff(serviceID int, tariff *ds.ServiceTariff) error {
+	if _, err := r.GetTransportService(serviceID); err != nil {
+		return err
+	}
+	if !tariff.ValidFrom.After(time.Now()) {
+		return fmt.Errorf("дата начала действия тарифа должна быть в будущем")
+	}
+	if tariff.Price <= 0 || tariff.DeliveryDays <= 0 {
+		return fmt.Errorf("цена и срок доставки должны быть больше 0")
+	}
+
+	tariff.TransportServiceID = serviceID
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return insertServiceTariff(tx, tariff)
+	})
+}
+
+// ApplyDueTariffs - перенос наступивших запланированных тарифов в карточки услуг.
+// Возвращает количество обновлённых услуг.
+func (r *Repository) ApplyDueTariffs() (int, error) {
+	var services []ds.TransportService
+	if err := r.db.Find(&services).Error; err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	updated := 0
+	for _, service := range services {
+		tariff, err := r.GetServiceTariffAt(service.ID, now)
+		if err != nil || tariff.Version == service.TariffVersion {
+			continue
+		}
+		current := tariff.ApplyTo(service)
+		err = r.db.Model(&current).Select("price", "delivery_days", "tariff_version",
+			"distance_rate", "weight_rate", "volume_rate", "distance_per_day", "min_delivery_days",
+			"max_length", "max_width", "max_height",
+			"complexity_factor", "complexity_cap", "complexity_days_divisor", "volumetric_divisor").
+			Updates(&current).Error
+		if err != nil {
+			return updated, err
+		}
+		updated++
+	}
+	return updated, nil
+}
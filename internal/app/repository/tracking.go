@@ -0,0 +1,247 @@
+package repository
+
+import (
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"rip-go-app/internal/app/calculator"
+	"rip-go-app/internal/app/ds"
+	"rip-go-app/internal/app/workflow"
+)
+
+// ==================== ОТСЛЕЖИВАНИЕ ГРУЗА ====================
+
+// trackingAlphabet - символы номера отслеживания (без похожих 0/O, 1/I/L)
+const trackingAlphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+
+// newTrackingNumber - публичный номер отслеживания вида RL-XXXX-XXXX-XX
+func newTrackingNumber() (string, error) {
+	buf := make([]byte, 10)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	code := make([]byte, len(buf))
+	for i, b := range buf {
+		code[i] = trackingAlphabet[int(b)%len(trackingAlphabet)]
+	}
+	return fmt.Sprintf("RL-%s-%s-%s", code[:4], code[4:8], code[8:]), nil
+}
+
+// TrackingInfo - состояние доставки для клиента
+type TrackingInfo struct {
+	TrackingNumber string                  `json:"tracking_number"`
+	Status         string                  `json:"status"`
+	FromCity       string                  `json:"from_city"`
+	ToCity         string                  `json:"to_city"`
+	CurrentCity    string                  `json:"current_city,omitempty"`
+	Checkpoints    []ds.TrackingCheckpoint `json:"checkpoints"`
+	// Оставшийся путь и ожидаемая дата доставки (для доставленного груза — фактическая)
+	Remaining *calculator.RemainingTransit `json:"remaining,omitempty"`
+	ETA       *time.Time                   `json:"eta,omitempty"`
+}
+
+// trackableStatuses - статусы, в которых по заявке можно вносить отметки
+var trackableStatuses = map[string]bool{
+	ds.StatusCompleted: true,
+	ds.StatusShipped:   true,
+}
+
+// getTrackedRequest - заявка со всем необходимым для расчета прогноза
+func (r *Repository) getTrackedRequest(query string, args ...interface{}) (ds.LogisticRequest, error) {
+	var order ds.LogisticRequest
+	err := r.db.Preload("Services.TransportService", withDeleted).Preload("Legs", orderLegsBySequence).
+		Preload("Legs.TransportService", withDeleted).Where(query, args...).First(&order).Error
+	if err != nil {
+		return ds.LogisticRequest{}, fmt.Errorf("заявка не найдена")
+	}
+	return order, nil
+}
+
+// GetTrackingCheckpoints - отметки по заявке в хронологическом порядке
+func (r *Repository) GetTrackingCheckpoints(orderID int) ([]ds.TrackingCheckpoint, error) {
+	var checkpoints []ds.TrackingCheckpoint
+	err := r.db.Where("logistic_request_id = ?", orderID).Order("occurred_at, id").Find(&checkpoints).Error
+	return checkpoints, err
+}
+
+// AddTrackingCheckpoint - отметка о движении груза. Первая отметка переводит заявку в shipped,
+// отметка delivered — в delivered; переходы проходят через workflow и пишутся в журнал.
+func (r *Repository) AddTrackingCheckpoint(orderID int, cp *ds.TrackingCheckpoint, actor workflow.Actor) error {
+	if !ds.CheckpointTypes[cp.EventType] {
+		return fmt.Errorf("неизвестный тип отметки: %s", cp.EventType)
+	}
+
+	order, err := r.getTrackedRequest("id = ?", orderID)
+	if err != nil {
+		return err
+	}
+	if !trackableStatuses[order.Status] {
+		return fmt.Errorf("отметки можно добавлять только к завершённым и отправленным заявкам")
+	}
+
+	city, err := r.FindCity(cp.City)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if cp.OccurredAt.IsZero() {
+		cp.OccurredAt = now
+	}
+	if cp.OccurredAt.After(now) {
+		return fmt.Errorf("время отметки не может быть в будущем")
+	}
+
+	cp.ID = 0
+	cp.LogisticRequestID = orderID
+	cp.City = city.Name
+	cp.CityID = &city.ID
+	if actor.UserID != 0 {
+		cp.CreatedByID = &actor.UserID
+	}
+
+	// Переходы статуса, которые вызывает отметка
+	var next []string
+	if order.Status == ds.StatusCompleted {
+		next = append(next, ds.StatusShipped)
+	}
+	if cp.EventType == ds.CheckpointDelivered {
+		next = append(next, ds.StatusDelivered)
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(cp).Error; err != nil {
+			return err
+		}
+		if err := recordEvent(tx, actor.UserID, ds.LogisticRequestEvent{
+			LogisticRequestID: orderID,
+			Type:              ds.EventCheckpointAdded,
+			SubjectID:         &cp.ID,
+			Field:             cp.EventType,
+			NewValue:          cp.City,
+			Comment:           cp.Note,
+		}); err != nil {
+			return err
+		}
+		for _, status := range next {
+			from := order.Status
+			if err := workflow.Apply(&order, status, actor, cp.OccurredAt); err != nil {
+				return err
+			}
+			if err := saveStatus(tx, &order); err != nil {
+				return err
+			}
+			if err := recordStatusChange(tx, actor.UserID, orderID, from, status, ""); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetTracking - отслеживание заявки по ID
+func (r *Repository) GetTracking(orderID int) (TrackingInfo, error) {
+	order, err := r.getTrackedRequest("id = ?", orderID)
+	if err != nil {
+		return TrackingInfo{}, err
+	}
+	return r.trackingInfo(order)
+}
+
+// GetTrackingByNumber - отслеживание заявки по публичному номеру
+func (r *Repository) GetTrackingByNumber(number string) (TrackingInfo, error) {
+	order, err := r.getTrackedRequest("tracking_number = ?", number)
+	if err != nil {
+		return TrackingInfo{}, err
+	}
+	return r.trackingInfo(order)
+}
+
+// trackingInfo - отметки и прогноз доставки по заявке
+func (r *Repository) trackingInfo(order ds.LogisticRequest) (TrackingInfo, error) {
+	checkpoints, err := r.GetTrackingCheckpoints(order.ID)
+	if err != nil {
+		return TrackingInfo{}, err
+	}
+
+	info := TrackingInfo{
+		Status:      order.Status,
+		FromCity:    order.FromCity,
+		ToCity:      order.ToCity,
+		Checkpoints: checkpoints,
+	}
+	if order.TrackingNumber != nil {
+		info.TrackingNumber = *order.TrackingNumber
+	}
+	if len(checkpoints) > 0 {
+		info.CurrentCity = checkpoints[len(checkpoints)-1].City
+	}
+
+	switch {
+	case order.Status == ds.StatusDelivered:
+		info.Remaining = &calculator.RemainingTransit{}
+		info.ETA = order.DeliveredAt
+	case !trackableStatuses[order.Status]:
+		// До одобрения заявки прогноз не строится
+	case len(checkpoints) == 0:
+		// Груз ещё не забран: прогноз по сроку из расчета заявки
+		if order.CompletedAt != nil {
+			eta := order.CompletedAt.AddDate(0, 0, order.TotalDays)
+			info.ETA = &eta
+		}
+	default:
+		last := checkpoints[len(checkpoints)-1]
+		legs := remainingLegs(order, checkpoints)
+		if len(legs) == 0 {
+			info.Remaining = &calculator.RemainingTransit{}
+			info.ETA = &last.OccurredAt
+			break
+		}
+		remaining, err := calculator.NewDeliveryCalculator(r).EstimateRemaining(legs, last.City)
+		if err != nil {
+			return TrackingInfo{}, err
+		}
+		eta := last.OccurredAt.AddDate(0, 0, remaining.Days)
+		info.Remaining = &remaining
+		info.ETA = &eta
+	}
+	return info, nil
+}
+
+// remainingLegs - ещё не пройденные плечи маршрута по отметкам.
+// Без сохранённого маршрута путь — одно плечо услугой с наибольшим сроком доставки.
+func remainingLegs(order ds.LogisticRequest, checkpoints []ds.TrackingCheckpoint) []calculator.RouteLeg {
+	if len(order.Legs) == 0 {
+		var slowest *ds.LogisticRequestService
+		for i := range order.Services {
+			if slowest == nil || order.Services[i].DeliveryDays > slowest.DeliveryDays {
+				slowest = &order.Services[i]
+			}
+		}
+		last := checkpoints[len(checkpoints)-1]
+		if slowest == nil || last.City == order.ToCity {
+			return nil
+		}
+		return []calculator.RouteLeg{{Service: slowest.TransportService, FromCity: order.FromCity, ToCity: order.ToCity}}
+	}
+
+	// Текущее плечо: город отметки в начале плеча — груз на нём, в конце — на следующем
+	current := 0
+	for _, cp := range checkpoints {
+		for i, leg := range order.Legs {
+			if cp.City == leg.FromCity && i >= current {
+				current = i
+			}
+			if cp.City == leg.ToCity && i+1 > current {
+				current = i + 1
+			}
+		}
+	}
+
+	legs := make([]calculator.RouteLeg, 0, len(order.Legs)-current)
+	for _, leg := range order.Legs[current:] {
+		legs = append(legs, calculator.RouteLeg{Service: leg.TransportService, FromCity: leg.FromCity, ToCity: leg.ToCity})
+	}
+	return legs
+}
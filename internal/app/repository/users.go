@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"rip-go-app/internal/app/ds"
+)
+
+// ==================== УПРАВЛЕНИЕ ПОЛЬЗОВАТЕЛЯМИ ====================
+
+// ErrUserTokenInvalid - одноразовый токен не найден, истёк, уже использован или выдан не этому пользователю
+var ErrUserTokenInvalid = errors.New("токен недействителен или уже использован")
+
+// UserFilter - фильтры списка пользователей
+type UserFilter struct {
+	Search   string // часть логина, имени или email
+	Role     string
+	Disabled *bool
+}
+
+// userSorting - поля сортировки списка пользователей
+var userSorting = sortOptions[ds.User]{
+	columns: map[string]sortColumn[ds.User]{
+		"created_at": {"created_at", func(u ds.User) any { return u.CreatedAt }},
+		"login":      {"login", func(u ds.User) any { return u.Login }},
+	},
+	defaultSort: "-created_at",
+	id:          func(u ds.User) int { return u.ID },
+}
+
+// ListUsers - страница пользователей с поиском и фильтрами
+func (r *Repository) ListUsers(filter UserFilter, page PageRequest) (Page[ds.User], error) {
+	query := r.db.Model(&ds.User{})
+	if filter.Search != "" {
+		text := "%" + filter.Search + "%"
+		query = query.Where("login ILIKE ? OR name ILIKE ? OR email ILIKE ?", text, text, text)
+	}
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+	if filter.Disabled != nil {
+		if *filter.Disabled {
+			query = query.Where("disabled_at IS NOT NULL")
+		} else {
+			query = query.Where("disabled_at IS NULL")
+		}
+	}
+	return paginate(query, page, userSorting)
+}
+
+// UpdateUserRole - смена роли пользователя
+func (r *Repository) UpdateUserRole(id int, role string) (ds.User, error) {
+	if !ds.Roles[role] {
+		return ds.User{}, fmt.Errorf("неизвестная роль: %s", role)
+	}
+	return r.updateUser(id, map[string]interface{}{"role": role})
+}
+
+// SetUserDisabled - блокировка или разблокировка пользователя
+func (r *Repository) SetUserDisabled(id int, disabled bool) (ds.User, error) {
+	var disabledAt *time.Time
+	if disabled {
+		now := time.Now()
+		disabledAt = &now
+	}
+	return r.updateUser(id, map[string]interface{}{"disabled_at": disabledAt})
+}
+
+// updateUser - изменение полей пользователя с возвратом обновлённой записи
+func (r *Repository) updateUser(id int, fields map[string]interface{}) (ds.User, error) {
+	var user ds.User
+	res := r.db.Model(&user).Clauses(clause.Returning{}).Where("id = ?", id).Updates(fields)
+	if res.Error != nil {
+		return ds.User{}, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ds.User{}, fmt.Errorf("пользователь не найден")
+	}
+	return user, nil
+}
+
+// CreateInvite - сохранение приглашения (токен уже захеширован)
+func (r *Repository) CreateInvite(invite *ds.UserToken) error {
+	invite.Purpose = ds.UserTokenInvite
+	return r.db.Create(invite).Error
+}
+
+// CreatePasswordResetToken - сброс пароля пользователя: прежний пароль перестаёт действовать,
+// ранее выданные токены сброса аннулируются, сохраняется новый
+func (r *Repository) CreatePasswordResetToken(token *ds.UserToken) error {
+	if token.UserID == nil {
+		return fmt.Errorf("не указан пользователь")
+	}
+	token.Purpose = ds.UserTokenPasswordReset
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&ds.User{}).Where("id = ?", *token.UserID).Update("password_reset_required", true)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return fmt.Errorf("пользователь не найден")
+		}
+		if err := tx.Model(&ds.UserToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", *token.UserID, ds.UserTokenPasswordReset).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Create(token).Error
+	})
+}
+
+// takeUserToken - действующий одноразовый токен по хешу (строка блокируется до конца транзакции)
+func takeUserToken(tx *gorm.DB, tokenHash, purpose string) (ds.UserToken, error) {
+	var token ds.UserToken
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", tokenHash, purpose, time.Now()).
+		First(&token).Error
+	if err != nil {
+		return ds.UserToken{}, ErrUserTokenInvalid
+	}
+	return token, tx.Model(&token).Update("used_at", time.Now()).Error
+}
+
+// CreateUserWithInvite - регистрация по приглашению: роль берется из приглашения, приглашение гасится
+func (r *Repository) CreateUserWithInvite(user *ds.User, tokenHash string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		invite, err := takeUserToken(tx, tokenHash, ds.UserTokenInvite)
+		if err != nil {
+			return err
+		}
+		if invite.Email != "" && !strings.EqualFold(invite.Email, user.Email) {
+			return fmt.Errorf("%w: приглашение выдано на другой email", ErrUserTokenInvalid)
+		}
+		user.Role = invite.Role
+		return (&Repository{db: tx}).CreateUser(user)
+	})
+}
+
+// ResetPassword - установка нового пароля (уже захешированного) по токену сброса
+func (r *Repository) ResetPassword(tokenHash, hashedPassword string) (ds.User, error) {
+	var user ds.User
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		token, err := takeUserToken(tx, tokenHash, ds.UserTokenPasswordReset)
+		if err != nil {
+			return err
+		}
+		res := tx.Model(&user).Clauses(clause.Returning{}).Where("id = ?", *token.UserID).
+			Updates(map[string]interface{}{"password": hashedPassword, "password_reset_required": false})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return fmt.Errorf("пользователь не найден")
+		}
+		return nil
+	})
+	return user, err
+}
+
+// PurgeExpiredUserTokens - удаление истёкших приглашений и токенов сброса пароля
+func (r *Repository) PurgeExpiredUserTokens() (int64, error) {
+	res := r.db.Where("expires_at < ?", time.Now()).Delete(&ds.UserToken{})
+	return res.RowsAffected, res.Error
+}
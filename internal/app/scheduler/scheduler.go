@@ -0,0 +1,118 @@
+// Package scheduler - периодические фоновые задачи сервера.
+// Перед запуском задача берёт аренду в БД на свой интервал, поэтому при нескольких репликах
+// за интервал задачу выполняет только одна из них.
+package scheduler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"rip-go-app/internal/app/ds"
+)
+
+// Job - фоновая задача; Run возвращает краткий итог для истории запусков
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) (string, error)
+}
+
+// Store - хранилище аренды и истории запусков (реализует repository.Repository)
+type Store interface {
+	AcquireJobLease(name, holder string, ttl time.Duration) (bool, error)
+	RecordJobRun(run *ds.JobRun) error
+}
+
+// Scheduler - планировщик фоновых задач
+type Scheduler struct {
+	store  Store
+	holder string
+	jobs   []Job
+}
+
+// New - создание планировщика; holder идентифицирует реплику (хост, pid, случайный суффикс)
+func New(store Store) *Scheduler {
+	host, _ := os.Hostname()
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	return &Scheduler{
+		store:  store,
+		holder: fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(suffix)),
+	}
+}
+
+// Register - добавление задачи (до вызова Start)
+func (s *Scheduler) Register(job Job) {
+	s.jobs = append(s.jobs, job)
+}
+
+// Jobs - зарегистрированные задачи
+func (s *Scheduler) Jobs() []Job {
+	return s.jobs
+}
+
+// Start - запуск всех задач в фоне; первый запуск сразу, далее раз в интервал
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, job := range s.jobs {
+		go s.loop(ctx, job)
+	}
+	logrus.Infof("scheduler started: %d jobs, holder %s", len(s.jobs), s.holder)
+}
+
+// loop - цикл одной задачи до отмены контекста
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+	for {
+		s.runOnce(ctx, job)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runOnce - запуск задачи, если удалось взять аренду; результат пишется в историю
+func (s *Scheduler) runOnce(ctx context.Context, job Job) {
+	acquired, err := s.store.AcquireJobLease(job.Name, s.holder, job.Interval)
+	if err != nil {
+		logrus.Errorf("job %s: failed to acquire lease: %v", job.Name, err)
+		return
+	}
+	if !acquired {
+		return
+	}
+
+	run := ds.JobRun{Name: job.Name, Holder: s.holder, StartedAt: time.Now()}
+	result, err := s.safeRun(ctx, job)
+	finished := time.Now()
+	run.FinishedAt = &finished
+	run.Result = result
+	if err != nil {
+		run.Status = ds.JobRunFailed
+		run.Error = err.Error()
+		logrus.Errorf("job %s failed: %v", job.Name, err)
+	} else {
+		run.Status = ds.JobRunSucceeded
+	}
+
+	if err := s.store.RecordJobRun(&run); err != nil {
+		logrus.Errorf("job %s: failed to record run: %v", job.Name, err)
+	}
+}
+
+// safeRun - выполнение задачи с перехватом паники, чтобы упавшая задача не остановила сервер
+func (s *Scheduler) safeRun(ctx context.Context, job Job) (result string, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return job.Run(ctx)
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"time"
 
+	"github.com/google/uuid"
 	"rip-go-app/internal/app/auth"
 	"rip-go-app/internal/app/ds"
 	"rip-go-app/internal/app/repository"
@@ -11,16 +12,18 @@ import (
 
 // AuthService - сервис авторизации
 type AuthService struct {
-	repo       *repository.Repository
-	jwtService *auth.JWTService
+	repo        *repository.Repository
+	jwtService  *auth.JWTService
+	revocations auth.RevocationStore
 }
 
-// NewAuthService - создание нового сервиса авторизации
-// Лаб7/требование: авторизация только по JWT, без Redis-сессий.
-func NewAuthService(repo *repository.Repository, jwtService *auth.JWTService) *AuthService {
+// NewAuthService - создание нового сервиса авторизации.
+// revocations - хранилище отзыва токенов (Redis, Postgres или память процесса).
+func NewAuthService(repo *repository.Repository, jwtService *auth.JWTService, revocations auth.RevocationStore) *AuthService {
 	return &AuthService{
-		repo:       repo,
-		jwtService: jwtService,
+		repo:        repo,
+		jwtService:  jwtService,
+		revocations: revocations,
 	}
 }
 
@@ -31,7 +34,8 @@ type RegisterRequest struct {
 	Password string `json:"password" binding:"required,min=6"`
 	Name     string `json:"name" binding:"required"`
 	Phone    string `json:"phone"`
-	Role     string `json:"role"`
+	// Приглашение менеджера; без него регистрируется покупатель
+	InviteToken string `json:"invite_token"`
 }
 
 // LoginRequest - запрос на вход
@@ -40,6 +44,12 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// ClientInfo - устройство, с которого выполнен вход (для списка сессий)
+type ClientInfo struct {
+	UserAgent string
+	IP        string
+}
+
 // AuthResponse - ответ авторизации
 type AuthResponse struct {
 	AccessToken  string    `json:"access_token"`
@@ -49,57 +59,42 @@ type AuthResponse struct {
 }
 
 // Register - регистрация пользователя
-func (s *AuthService) Register(req RegisterRequest) (*AuthResponse, error) {
+func (s *AuthService) Register(req RegisterRequest, client ClientInfo) (*AuthResponse, error) {
 	// Проверяем, что пользователь с таким логином не существует
 	_, err := s.repo.GetUserByLogin(req.Login)
 	if err == nil {
 		return nil, errors.New("user with this login already exists")
 	}
 
-	// Устанавливаем роль по умолчанию
-	role := req.Role
-	if role == "" {
-		role = ds.RoleBuyer
-	}
-
-	// Создаем пользователя
+	// Создаем пользователя: роль при самостоятельной регистрации всегда buyer,
+	// другую роль дает только приглашение администратора
 	user := ds.User{
 		Login:    req.Login,
 		Email:    req.Email,
 		Password: req.Password, // пароль будет захеширован в handler
 		Name:     req.Name,
 		Phone:    req.Phone,
-		Role:     role,
+		Role:     ds.RoleBuyer,
 	}
 
-	if err := s.repo.CreateUser(&user); err != nil {
+	if req.InviteToken != "" {
+		err := s.repo.CreateUserWithInvite(&user, hashToken(req.InviteToken))
+		if errors.Is(err, repository.ErrUserTokenInvalid) {
+			return nil, ErrInvalidInvite
+		}
+		if err != nil {
+			return nil, errors.New("failed to create user")
+		}
+	} else if err := s.repo.CreateUser(&user); err != nil {
 		return nil, errors.New("failed to create user")
 	}
 
-	// Генерируем токены
-	accessToken, err := s.jwtService.GenerateAccessToken(user.UUID, user.Role)
-	if err != nil {
-		return nil, errors.New("failed to generate access token")
-	}
-
-	refreshToken, err := s.jwtService.GenerateRefreshToken(user.UUID, user.Role)
-	if err != nil {
-		return nil, errors.New("failed to generate refresh token")
-	}
-
-	// Убираем пароль из ответа
-	user.Password = ""
-
-	return &AuthResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		User:         user,
-		ExpiresAt:    time.Now().Add(15 * time.Minute), // время жизни access токена
-	}, nil
+	// Вход открывает новую сессию
+	return s.issueTokens(user, uuid.New().String(), client)
 }
 
 // Login - вход пользователя
-func (s *AuthService) Login(req LoginRequest, hashedPassword string) (*AuthResponse, error) {
+func (s *AuthService) Login(req LoginRequest, hashedPassword string, client ClientInfo) (*AuthResponse, error) {
 	// Получаем пользователя по логину
 	user, err := s.repo.GetUserByLogin(req.Login)
 	if err != nil {
@@ -110,18 +105,102 @@ func (s *AuthService) Login(req LoginRequest, hashedPassword string) (*AuthRespo
 	if user.Password != hashedPassword {
 		return nil, errors.New("invalid credentials")
 	}
+	if user.DisabledAt != nil {
+		return nil, ErrUserDisabled
+	}
+	if user.PasswordResetRequired {
+		return nil, ErrPasswordResetRequired
+	}
 
-	// Генерируем токены
-	accessToken, err := s.jwtService.GenerateAccessToken(user.UUID, user.Role)
+	// Вход открывает новую сессию
+	return s.issueTokens(user, uuid.New().String(), client)
+}
+
+// Logout - выход пользователя по claims проверенного access токена: токен отзывается до истечения срока,
+// сессия токена завершается (её refresh токены больше не обмениваются)
+func (s *AuthService) Logout(claims *auth.JWTClaims) error {
+	if err := s.revocations.RevokeToken(claims.ID, time.Until(claims.ExpiresAt.Time)); err != nil {
+		return err
+	}
+	if claims.SessionID == "" {
+		return nil
+	}
+	user, err := s.repo.GetUserByUUID(claims.UserUUID)
+	if err != nil {
+		return errors.New("user not found")
+	}
+	if err := s.RevokeSession(user.ID, claims.SessionID); err != nil && !errors.Is(err, repository.ErrSessionNotFound) {
+		return err
+	}
+	return nil
+}
+
+// RevokeSession - завершение сессии: её refresh токены больше не обмениваются,
+// а выданные в ней access токены (по sid) отклоняются до истечения срока
+func (s *AuthService) RevokeSession(userID int, sessionID string) error {
+	if err := s.repo.RevokeSession(userID, sessionID); err != nil {
+		return err
+	}
+	return s.revocations.RevokeSessionTokens(sessionID, s.jwtService.AccessTokenExpiration())
+}
+
+// RevokeOtherSessions - завершение всех сессий пользователя, кроме keepSessionID
+func (s *AuthService) RevokeOtherSessions(userID int, keepSessionID string) error {
+	sessions, err := s.repo.RevokeOtherSessions(userID, keepSessionID)
+	if err != nil {
+		return err
+	}
+	for _, sessionID := range sessions {
+		if err := s.revocations.RevokeSessionTokens(sessionID, s.jwtService.AccessTokenExpiration()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RevokeAllTokens - отзыв всех токенов пользователя: новое поколение токенов делает недействительными
+// все выданные access и refresh токены, сессии пользователя завершаются. Возвращает новое поколение.
+func (s *AuthService) RevokeAllTokens(user ds.User) (int, error) {
+	generation, err := s.revocations.BumpTokenGeneration(user.UUID)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := s.repo.RevokeOtherSessions(user.ID, ""); err != nil {
+		return 0, err
+	}
+	return generation, nil
+}
+
+// issueTokens - выдача пары токенов в сессии sessionID; refresh токен учитывается на сервере
+func (s *AuthService) issueTokens(user ds.User, sessionID string, client ClientInfo) (*AuthResponse, error) {
+	generation, err := s.revocations.TokenGeneration(user.UUID)
+	if err != nil {
+		return nil, errors.New("failed to get token generation")
+	}
+
+	accessToken, err := s.jwtService.GenerateAccessToken(user.UUID, user.Role, sessionID, generation)
 	if err != nil {
 		return nil, errors.New("failed to generate access token")
 	}
 
-	refreshToken, err := s.jwtService.GenerateRefreshToken(user.UUID, user.Role)
+	refreshToken, claims, err := s.jwtService.GenerateRefreshToken(user.UUID, user.Role, sessionID, generation)
 	if err != nil {
 		return nil, errors.New("failed to generate refresh token")
 	}
 
+	stored := ds.RefreshToken{
+		ID:        claims.ID,
+		FamilyID:  sessionID,
+		UserID:    user.ID,
+		UserAgent: client.UserAgent,
+		IP:        client.IP,
+		IssuedAt:  claims.IssuedAt.Time,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}
+	if err := s.repo.CreateRefreshToken(&stored); err != nil {
+		return nil, errors.New("failed to store refresh token")
+	}
+
 	// Убираем пароль из ответа
 	user.Password = ""
 
@@ -129,40 +208,64 @@ func (s *AuthService) Login(req LoginRequest, hashedPassword string) (*AuthRespo
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
 		User:         user,
-		ExpiresAt:    time.Now().Add(15 * time.Minute), // время жизни access токена
+		ExpiresAt:    time.Now().Add(s.jwtService.AccessTokenExpiration()),
 	}, nil
 }
 
-// Logout - выход пользователя
-func (s *AuthService) Logout(userUUID, accessToken string) error {
-	// Stateless JWT: сервер не хранит сессии. Выход — это “забыть токен” на клиенте.
-	// (Можно добавить blacklist по jti, но по требованию убираем Redis.)
-	_ = userUUID
-	_ = accessToken
-	return nil
-}
-
-// RefreshTokens - обновление токенов
-func (s *AuthService) RefreshTokens(refreshToken string) (*AuthResponse, error) {
+// RefreshTokens - обновление токенов. Refresh токен одноразовый: он обменивается на новую пару
+// в той же сессии, а повторное предъявление обменянного токена отзывает всю сессию.
+func (s *AuthService) RefreshTokens(refreshToken string, client ClientInfo) (*AuthResponse, error) {
 	// Валидируем refresh токен
 	claims, err := s.jwtService.ValidateToken(refreshToken)
 	if err != nil {
 		return nil, errors.New("invalid refresh token")
 	}
 
-	if claims.Type != "refresh" {
+	if claims.Type != "refresh" || claims.SessionID == "" {
 		return nil, errors.New("invalid token type")
 	}
 
-	// Получаем пользователя
+	// Токены отозванного поколения не обмениваются
+	if revoked, err := auth.IsRevoked(s.revocations, claims); err != nil || revoked {
+		return nil, errors.New("invalid refresh token")
+	}
+
+	// Получаем пользователя (роль в новых токенах — текущая)
 	user, err := s.repo.GetUserByUUID(claims.UserUUID)
 	if err != nil {
 		return nil, errors.New("user not found")
 	}
+	if user.DisabledAt != nil {
+		return nil, ErrUserDisabled
+	}
 
-	// Генерируем новые токены (stateless, без Redis)
-	accessToken, newRefreshToken, err := s.jwtService.RefreshTokenPair(refreshToken)
+	accessToken, err := s.jwtService.GenerateAccessToken(user.UUID, user.Role, claims.SessionID, claims.Generation)
 	if err != nil {
+		return nil, errors.New("failed to generate access token")
+	}
+	newRefreshToken, newClaims, err := s.jwtService.GenerateRefreshToken(user.UUID, user.Role, claims.SessionID, claims.Generation)
+	if err != nil {
+		return nil, errors.New("failed to generate refresh token")
+	}
+
+	next := ds.RefreshToken{
+		ID:        newClaims.ID,
+		UserAgent: client.UserAgent,
+		IP:        client.IP,
+		IssuedAt:  newClaims.IssuedAt.Time,
+		ExpiresAt: newClaims.ExpiresAt.Time,
+	}
+	if err := s.repo.RotateRefreshToken(claims.ID, &next); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRefreshTokenReused):
+			// Access токены украденной сессии тоже перестают приниматься
+			if err := s.revocations.RevokeSessionTokens(claims.SessionID, s.jwtService.AccessTokenExpiration()); err != nil {
+				return nil, errors.New("failed to revoke session")
+			}
+			return nil, errors.New("refresh token reuse detected, session revoked")
+		case errors.Is(err, repository.ErrRefreshTokenInvalid):
+			return nil, errors.New("invalid refresh token")
+		}
 		return nil, errors.New("failed to refresh token pair")
 	}
 
@@ -173,10 +276,15 @@ func (s *AuthService) RefreshTokens(refreshToken string) (*AuthResponse, error)
 		AccessToken:  accessToken,
 		RefreshToken: newRefreshToken,
 		User:         user,
-		ExpiresAt:    time.Now().Add(15 * time.Minute), // время жизни access токена
+		ExpiresAt:    time.Now().Add(s.jwtService.AccessTokenExpiration()),
 	}, nil
 }
 
+// JWKS - открытые ключи для проверки наших токенов другими сервисами
+func (s *AuthService) JWKS() auth.JWKS {
+	return s.jwtService.JWKS()
+}
+
 // ValidateAccess - проверка доступа к ресурсу
 func (s *AuthService) ValidateAccess(userUUID, resource string) (bool, error) {
 	// Stateless JWT: доступ определяется валидностью JWT и ролью в claims.
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"rip-go-app/internal/app/ds"
+	"rip-go-app/internal/app/repository"
+)
+
+// Сроки действия одноразовых токенов
+const (
+	inviteTTL        = 72 * time.Hour
+	passwordResetTTL = 24 * time.Hour
+)
+
+// Ошибки управления пользователями; проверяются через errors.Is
+var (
+	ErrUserDisabled          = errors.New("user is disabled")
+	ErrPasswordResetRequired = errors.New("password reset required")
+	ErrInvalidInvite         = errors.New("invalid or expired invite")
+	ErrInvalidResetToken     = errors.New("invalid or expired password reset token")
+	ErrUnknownRole           = errors.New("unknown role")
+)
+
+// newOneTimeToken - случайный одноразовый токен и его хеш для хранения в БД
+func newOneTimeToken() (token, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(buf)
+	return token, hashToken(token), nil
+}
+
+// hashToken - хеш одноразового токена (в БД токены в открытом виде не хранятся)
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateManagerInvite - приглашение менеджера на email. Возвращает токен (показывается один раз)
+// и сохранённое приглашение.
+func (s *AuthService) CreateManagerInvite(email string, adminID int) (string, ds.UserToken, error) {
+	token, hash, err := newOneTimeToken()
+	if err != nil {
+		return "", ds.UserToken{}, err
+	}
+	invite := ds.UserToken{
+		TokenHash:   hash,
+		Email:       email,
+		Role:        ds.RoleManager,
+		CreatedByID: adminID,
+		ExpiresAt:   time.Now().Add(inviteTTL),
+	}
+	if err := s.repo.CreateInvite(&invite); err != nil {
+		return "", ds.UserToken{}, err
+	}
+	return token, invite, nil
+}
+
+// ForcePasswordReset - принудительный сброс пароля: вход запрещен до установки нового пароля,
+// все токены пользователя отзываются. Возвращает токен сброса (показывается один раз).
+func (s *AuthService) ForcePasswordReset(user ds.User, adminID int) (string, ds.UserToken, error) {
+	token, hash, err := newOneTimeToken()
+	if err != nil {
+		return "", ds.UserToken{}, err
+	}
+	reset := ds.UserToken{
+		TokenHash:   hash,
+		UserID:      &user.ID,
+		CreatedByID: adminID,
+		ExpiresAt:   time.Now().Add(passwordResetTTL),
+	}
+	if err := s.repo.CreatePasswordResetToken(&reset); err != nil {
+		return "", ds.UserToken{}, err
+	}
+	if _, err := s.RevokeAllTokens(user); err != nil {
+		return "", ds.UserToken{}, err
+	}
+	return token, reset, nil
+}
+
+// ResetPassword - установка нового пароля (уже захешированного) по токену сброса
+func (s *AuthService) ResetPassword(token, hashedPassword string) error {
+	_, err := s.repo.ResetPassword(hashToken(token), hashedPassword)
+	if errors.Is(err, repository.ErrUserTokenInvalid) {
+		return ErrInvalidResetToken
+	}
+	return err
+}
+
+// ChangeUserRole - смена роли; токены со старой ролью отзываются
+func (s *AuthService) ChangeUserRole(user ds.User, role string) (ds.User, error) {
+	if !ds.Roles[role] {
+		return ds.User{}, ErrUnknownRole
+	}
+	updated, err := s.repo.UpdateUserRole(user.ID, role)
+	if err != nil {
+		return ds.User{}, err
+	}
+	if _, err := s.RevokeAllTokens(updated); err != nil {
+		return ds.User{}, err
+	}
+	return updated, nil
+}
+
+// SetUserDisabled - блокировка (с отзывом всех токенов) или разблокировка пользователя
+func (s *AuthService) SetUserDisabled(user ds.User, disabled bool) (ds.User, error) {
+	updated, err := s.repo.SetUserDisabled(user.ID, disabled)
+	if err != nil {
+		return ds.User{}, err
+	}
+	if disabled {
+		if _, err := s.RevokeAllTokens(updated); err != nil {
+			return ds.User{}, err
+		}
+	}
+	return updated, nil
+}
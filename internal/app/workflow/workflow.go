@@ -0,0 +1,180 @@
+// Package workflow - жизненный цикл логистической заявки: допустимые переходы статусов,
+// кто может их выполнять и что меняется в заявке при переходе.
+// Все изменения статуса заявки выполняются через Apply.
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"rip-go-app/internal/app/ds"
+)
+
+// Ошибки перехода (проверяются через errors.Is)
+var (
+	ErrUnknownStatus     = errors.New("неизвестный статус")
+	ErrInvalidTransition = errors.New("недопустимый переход статуса")
+	ErrForbidden         = errors.New("недостаточно прав для смены статуса")
+)
+
+// Actor - пользователь, выполняющий переход
+type Actor struct {
+	UserID int
+	Role   string
+}
+
+// Исполнители перехода
+const (
+	byCreator = 1 << iota // создатель заявки
+	byManager             // менеджер (модератор)
+	byAdmin               // администратор
+)
+
+// Transition - допустимый переход статуса
+type Transition struct {
+	From string
+	To   string
+	by   int
+}
+
+// transitions - полная таблица переходов.
+//
+//	draft → formed → completed → shipped → delivered
+//	 ↓ ↑       ↓ ↘        ↓
+//	deleted  rejected  cancelled
+//
+// Удалённый черновик может восстановить администратор.
+var transitions = []Transition{
+	{ds.StatusDraft, ds.StatusFormed, byCreator},
+	{ds.StatusDraft, ds.StatusDeleted, byCreator | byAdmin},
+	{ds.StatusDeleted, ds.StatusDraft, byAdmin},
+	{ds.StatusFormed, ds.StatusCompleted, byManager | byAdmin},
+	{ds.StatusFormed, ds.StatusRejected, byManager | byAdmin},
+	{ds.StatusFormed, ds.StatusCancelled, byCreator | byManager | byAdmin},
+	{ds.StatusCompleted, ds.StatusShipped, byManager | byAdmin},
+	{ds.StatusCompleted, ds.StatusCancelled, byManager | byAdmin},
+	{ds.StatusShipped, ds.StatusDelivered, byManager | byAdmin},
+}
+
+// statuses - все статусы заявки
+var statuses = map[string]bool{
+	ds.StatusDraft:     true,
+	ds.StatusFormed:    true,
+	ds.StatusCompleted: true,
+	ds.StatusRejected:  true,
+	ds.StatusShipped:   true,
+	ds.StatusDelivered: true,
+	ds.StatusCancelled: true,
+	ds.StatusDeleted:   true,
+}
+
+// legacyStatuses - статусы старого API смены статуса и их соответствие единому набору
+var legacyStatuses = map[string]string{
+	"pending":    ds.StatusFormed,
+	"processing": ds.StatusCompleted,
+}
+
+// ParseStatus - приведение статуса (в том числе устаревшего) к единому набору
+func ParseStatus(status string) (string, error) {
+	if mapped, ok := legacyStatuses[status]; ok {
+		return mapped, nil
+	}
+	if !statuses[status] {
+		return "", fmt.Errorf("%w: %s", ErrUnknownStatus, status)
+	}
+	return status, nil
+}
+
+// IsFinal - статус, из которого нет переходов
+func IsFinal(status string) bool {
+	for _, t := range transitions {
+		if t.From == status {
+			return false
+		}
+	}
+	return true
+}
+
+// actorMask - в каких качествах пользователь выступает для заявки
+func actorMask(order ds.LogisticRequest, actor Actor) int {
+	mask := 0
+	if actor.UserID != 0 && actor.UserID == order.CreatorID {
+		mask |= byCreator
+	}
+	switch actor.Role {
+	case ds.RoleManager:
+		mask |= byManager
+	case ds.RoleAdmin:
+		mask |= byAdmin
+	}
+	return mask
+}
+
+// find - переход из from в to
+func find(from, to string) (Transition, bool) {
+	for _, t := range transitions {
+		if t.From == from && t.To == to {
+			return t, true
+		}
+	}
+	return Transition{}, false
+}
+
+// Check - может ли пользователь перевести заявку в статус to
+func Check(order ds.LogisticRequest, to string, actor Actor) error {
+	if !statuses[to] {
+		return fmt.Errorf("%w: %s", ErrUnknownStatus, to)
+	}
+	t, ok := find(order.Status, to)
+	if !ok {
+		return fmt.Errorf("%w: %s → %s", ErrInvalidTransition, order.Status, to)
+	}
+	if t.by&actorMask(order, actor) == 0 {
+		return fmt.Errorf("%w: %s → %s", ErrForbidden, order.Status, to)
+	}
+	return nil
+}
+
+// Available - статусы, в которые пользователь может перевести заявку
+func Available(order ds.LogisticRequest, actor Actor) []string {
+	mask := actorMask(order, actor)
+	next := make([]string, 0)
+	for _, t := range transitions {
+		if t.From == order.Status && t.by&mask != 0 {
+			next = append(next, t.To)
+		}
+	}
+	return next
+}
+
+// Apply - проверка и выполнение перехода с побочными эффектами (даты, модератор).
+// Заявка изменяется в памяти, сохранение — на стороне вызывающего.
+func Apply(order *ds.LogisticRequest, to string, actor Actor, now time.Time) error {
+	if err := Check(*order, to, actor); err != nil {
+		return err
+	}
+
+	switch to {
+	case ds.StatusDraft:
+		order.IsDraft = true
+		order.DeletedAt = gorm.DeletedAt{}
+	case ds.StatusFormed:
+		order.FormedAt = &now
+		order.IsDraft = false
+	case ds.StatusCompleted, ds.StatusRejected:
+		order.ModeratorID = &actor.UserID
+		order.CompletedAt = &now
+	case ds.StatusShipped:
+		order.ShippedAt = &now
+	case ds.StatusDelivered:
+		order.DeliveredAt = &now
+	case ds.StatusCancelled:
+		order.CancelledAt = &now
+	case ds.StatusDeleted:
+		order.DeletedAt = gorm.DeletedAt{Time: now, Valid: true}
+	}
+	order.Status = to
+	return nil
+}